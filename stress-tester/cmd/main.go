@@ -1,58 +1,468 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"stress-tester/internal/db"
+	"stress-tester/internal/dto"
+	"stress-tester/internal/entity"
+	"stress-tester/internal/plan"
+	"stress-tester/internal/pool"
+	"stress-tester/internal/stats"
 	"stress-tester/internal/usecase"
+	"strings"
+	"syscall"
+	"time"
 )
 
+// version is the version of the stress tester, kept with the runs in a results database.
+// It is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
 
-	url, requests, concurrency := handleFlags()
-	usecase.RoutineGet(*url, *requests, *concurrency)
+	args := os.Args[1:]
+	var err error
+	if len(args) > 0 && args[0] == "report" {
+		err = usecase.RoutineReport(handleReport(args[1:]))
+	} else {
+		var load dto.Load
+		if len(args) > 0 && args[0] == "run" {
+			load = handleRun(args[1:])
+		} else {
+			load = handleFlags(args)
+		}
+		load.Version = version
+		err = usecase.RoutineGet(interruptible(), load)
+	}
+	if err != nil {
+		fmt.Println(err)
+		slog.Error(err.Error())
+		switch {
+		case errors.Is(err, usecase.ErrInterrupted):
+			os.Exit(130)
+		case errors.Is(err, usecase.ErrThresholds):
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
 }
 
-func handleFlags() (url *string, requests *int, concurrency *int) {
+// interruptible returns a context canceled with usecase.ErrInterrupted on the first
+// SIGINT or SIGTERM, so the run stops and reports what it got so far, and exits the
+// program right away on the second one.
+func interruptible() context.Context {
+	ctx, interrupt := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		s := <-signals
+		fmt.Fprintln(os.Stderr, "Stopping, waiting for the requests in flight. Press Ctrl-C again to quit now.")
+		interrupt(fmt.Errorf("%w by signal: %s", usecase.ErrInterrupted, s))
+		<-signals
+		os.Exit(130)
+	}()
+	return ctx
+}
 
-	url = flag.String("url", "http://localhost:8080", "Url to be tested.")
-	requests = flag.Int("requests", 105, "Qt of requests.")
-	concurrency = flag.Int("concurrency", 10, "Qt of concurrent requests.")
+// headerFlag collects the values of a repeatable "Name: value" flag into an http.Header.
+type headerFlag http.Header
 
-	flag.Parse()
+func (h headerFlag) String() string {
+	return fmt.Sprint(http.Header(h))
+}
 
-	errors := []string{}
+func (h headerFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(k) == "" {
+		return fmt.Errorf("header %q must be in the form \"Name: value\"", value)
+	}
+	http.Header(h).Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	return nil
+}
+
+// stringsFlag collects the values of a repeatable flag.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return fmt.Sprint(*s)
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// outputFlag collects the values of a repeatable "format" or "format:path" flag.
+type outputFlag []plan.Output
+
+func (o *outputFlag) String() string {
+	return fmt.Sprint(*o)
+}
+
+func (o *outputFlag) Set(value string) error {
+	format, path, _ := strings.Cut(value, ":")
+	*o = append(*o, plan.Output{Format: format, Path: path})
+	return nil
+}
+
+// flags holds the command line flags, shared by the plain flags mode and the run command,
+// where they override the fields of the plan file.
+type flags struct {
+	set         *flag.FlagSet
+	url         *string
+	requests    *int
+	concurrency *int
+	duration    *time.Duration
+	rate        *float64
+	maxInFlight *int
+	method      *string
+	header      headerFlag
+	body        *string
+	bodyFile    *string
+	stages      *string
+	timeout     *time.Duration
+	grace       *time.Duration
+	interval    *time.Duration
+	precision   *int
+	percentiles *string
+	thresholds  stringsFlag
+	aborts      stringsFlag
+	outputs     outputFlag
+	store       *string
+	out         *string
+	gitSHA      *string
+	tags        stringsFlag
+}
+
+func newFlags(name string) *flags {
+	f := &flags{set: flag.NewFlagSet(name, flag.ExitOnError), header: headerFlag{}}
+	f.url = f.set.String("url", "http://localhost:8080", "Url to be tested.")
+	f.requests = f.set.Int("requests", 105, "Qt of requests.")
+	f.concurrency = f.set.Int("concurrency", 10, "Qt of concurrent requests.")
+	f.duration = f.set.Duration("duration", 0, "Run for this long instead of a fixed number of requests (e.g. 15m). Overrides --requests.")
+	f.rate = f.set.Float64("rate", 0, "Requests per second to schedule at a constant arrival rate, whatever the response times are.")
+	f.maxInFlight = f.set.Int("max-in-flight", 0, "Max requests waiting for a response when --rate is used. Defaults to --concurrency.")
+	f.method = f.set.String("method", http.MethodGet, "HTTP method of the requests.")
+	f.set.Var(f.header, "header", "Header sent with the requests, as \"Name: value\". Can be repeated.")
+	f.body = f.set.String("body", "", "Body sent with the requests.")
+	f.bodyFile = f.set.String("body-file", "", "File with the body sent with the requests.")
+	f.stages = f.set.String("stages", "", "Load profile as comma separated duration:workers stages (e.g. 2m:100,10m:100,1m:0). Overrides --concurrency, --requests and --duration.")
+	f.timeout = f.set.Duration("timeout", 0, "Max time to wait for each response (e.g. 5s). Zero waits forever.")
+	f.grace = f.set.Duration("grace", 10*time.Second, "Max time to wait for the requests in flight when the run stops early or is interrupted, before reporting without them. Zero waits for all of them.")
+	f.interval = f.set.Duration("interval", time.Second, "Width of the intervals the results over time are split in (e.g. 10s).")
+	f.precision = f.set.Int("precision", stats.DefaultDigits, "Significant digits the latencies are recorded with, between 1 and 5.")
+	f.percentiles = f.set.String("percentiles", "50,90,95,99,99.9,99.99", "Comma separated percentiles of the latencies in the report.")
+	f.set.Var(&f.thresholds, "threshold", "Pass/fail criterion of the run, as metric<value (e.g. p99<300ms, error_rate<1%, rps>200, status_5xx<10). Can be repeated. Exits with 2 when one is breached.")
+	f.set.Var(&f.aborts, "abort", "Condition that stops the run early, as a threshold optionally followed by the window it is checked over (e.g. \"error_rate>50% for 10s\", p95>5s). Can be repeated.")
+	f.set.Var(&f.outputs, "output", "Report output as format or format:path, format being text or json. Can be repeated. Defaults to text on the console.")
+	f.store = f.set.String("store", "sqlite", "Where the records of the run are kept: sqlite, an in-memory database, sqlite:path, a database file, memory, which only keeps their summaries, or file:path, which appends them to a JSON lines file.")
+	f.out = f.set.String("out", "", "Results database file the records and the metadata of the run are kept in, along with the runs already in it (e.g. results.db). Same as --store=sqlite:path.")
+	f.gitSHA = f.set.String("git-sha", "", "Git SHA of the system under test, kept with the run in the results database.")
+	f.set.Var(&f.tags, "tag", "Free-form tag kept with the run in the results database (e.g. env=staging). Can be repeated, adding to the tags of the plan.")
+	return f
+}
 
-	if *url == "" {
-		errors = append(errors, "url must not be empty")
+// apply overrides the fields of the plan with the flags in the given set, returning the
+// problems found. The method, headers and body apply to every target of the plan, while
+// the url can only override a plan with a single target.
+func (f *flags) apply(p *plan.Plan, set map[string]bool) []string {
+	errors := []string{}
+	if set["url"] {
+		switch len(p.Targets) {
+		case 0:
+			p.Targets = append(p.Targets, plan.Target{URL: *f.url})
+		case 1:
+			p.Targets[0].URL = *f.url
+		default:
+			errors = append(errors, "url can only override a plan with a single target")
+		}
+	}
+	if set["body"] && set["body-file"] && *f.body != "" && *f.bodyFile != "" {
+		errors = append(errors, "body and body-file can not be used together")
+	}
+	for i := range p.Targets {
+		t := &p.Targets[i]
+		if set["method"] {
+			t.Method = *f.method
+		}
+		if set["header"] {
+			if t.Headers == nil {
+				t.Headers = map[string]plan.HeaderValues{}
+			}
+			// A header of the flags replaces all the values of the plan for it, whatever
+			// the case the plan names it in.
+			for k := range t.Headers {
+				if _, ok := f.header[http.CanonicalHeaderKey(k)]; ok {
+					delete(t.Headers, k)
+				}
+			}
+			for k, values := range f.header {
+				t.Headers[k] = plan.HeaderValues(values)
+			}
+		}
+		if set["body"] && *f.body != "" {
+			t.Body, t.BodyFile = *f.body, ""
+		}
+		if set["body-file"] && *f.bodyFile != "" {
+			path, err := filepath.Abs(*f.bodyFile)
+			if err != nil {
+				errors = append(errors, err.Error())
+			}
+			t.Body, t.BodyFile = "", path
+		}
 	}
-	if *requests <= 0 {
-		errors = append(errors, "requests must be greater than 0")
+	if set["requests"] {
+		p.Load.Requests = *f.requests
 	}
-	if *concurrency <= 0 {
-		errors = append(errors, "concurrency must be greater than 0")
+	if set["concurrency"] {
+		p.Load.Concurrency = *f.concurrency
 	}
-	if len(errors) == 0 {
-		req, err := http.Get(*url)
+	if set["duration"] {
+		p.Load.Duration = plan.Duration(*f.duration)
+	}
+	if set["rate"] {
+		p.Load.Rate = *f.rate
+	}
+	if set["max-in-flight"] {
+		p.Load.MaxInFlight = *f.maxInFlight
+	}
+	if set["stages"] && *f.stages != "" {
+		stages, err := entity.ParseStages(*f.stages)
 		if err != nil {
 			errors = append(errors, err.Error())
 		}
-		if req != nil && req.StatusCode != 200 {
-			errors = append(errors, fmt.Sprintf("Status code should be 200, but is %d. Check the URL.", req.StatusCode))
+		p.Load.Stages = nil
+		for _, s := range stages {
+			p.Load.Stages = append(p.Load.Stages, plan.Stage{Duration: plan.Duration(s.Duration), Target: s.Target})
+		}
+	}
+	if set["timeout"] {
+		p.Timeout = plan.Duration(*f.timeout)
+	}
+	if set["grace"] {
+		p.Grace = plan.Duration(*f.grace)
+	}
+	if set["interval"] {
+		p.Interval = plan.Duration(*f.interval)
+	}
+	if set["precision"] {
+		p.Precision = *f.precision
+	}
+	if set["percentiles"] {
+		percentiles, err := entity.ParsePercentiles(*f.percentiles)
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+		p.Percentiles = percentiles
+	}
+	if set["threshold"] {
+		p.Thresholds = f.thresholds
+	}
+	if set["abort"] {
+		p.Abort = f.aborts
+	}
+	if set["output"] {
+		p.Outputs = f.outputs
+	}
+	if set["store"] {
+		kind, path, _ := strings.Cut(*f.store, ":")
+		p.Store = plan.Store{Kind: kind, Path: path}
+	}
+	if set["out"] && *f.out != "" {
+		if p.Store.Kind != "" && p.Store.Kind != "sqlite" {
+			errors = append(errors, fmt.Sprintf("out can not be used with the %s store", p.Store.Kind))
 		}
+		p.Store = plan.Store{Kind: "sqlite", Path: *f.out}
+	}
+	if set["git-sha"] {
+		p.GitSHA = *f.gitSHA
+	}
+	if set["tag"] {
+		p.Tags = append(p.Tags, f.tags...)
 	}
+	return errors
+}
+
+// handleFlags builds the test from the command line flags alone, every flag applying with
+// its default value when not given.
+func handleFlags(args []string) dto.Load {
+	f := newFlags("stresstester")
+	f.set.Parse(args)
+
+	set := map[string]bool{}
+	f.set.VisitAll(func(fl *flag.Flag) { set[fl.Name] = true })
 
+	p := plan.New()
+	return validate(p, f.apply(p, set))
+}
+
+// handleRun builds the test from the plan file given as the first argument of the run
+// command, with the flags given before or after it overriding the fields of the plan.
+func handleRun(args []string) dto.Load {
+	f := newFlags("stresstester run")
+	f.set.Parse(args)
+	if f.set.NArg() == 0 {
+		fail([]string{"run needs the path of a plan file"})
+	}
+	path := f.set.Arg(0)
+	f.set.Parse(f.set.Args()[1:])
+	if f.set.NArg() > 0 {
+		fail([]string{fmt.Sprintf("unexpected arguments %v", f.set.Args())})
+	}
+
+	p, err := plan.Read(path)
+	if err != nil {
+		fail([]string{err.Error()})
+	}
+
+	set := map[string]bool{}
+	f.set.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+
+	return validate(p, f.apply(p, set))
+}
+
+// handleReport builds the report of a run kept in a results database from the flags of
+// the report command, exiting the program listing the problems found in them.
+func handleReport(args []string) dto.Report {
+	set := flag.NewFlagSet("stresstester report", flag.ExitOnError)
+	from := set.String("from", "", "Results database file the run is kept in (e.g. results.db).")
+	run := set.String("run", "", "Id of the run to report. Defaults to the last run with all of the --tag.")
+	var tags stringsFlag
+	set.Var(&tags, "tag", "Tag the run must have. Can be repeated.")
+	list := set.Bool("list", false, "List the runs with all of the --tag instead of reporting one.")
+	endpoint := set.String("endpoint", "", "Only report the requests sent to the endpoint, or step of a journey, with this name.")
+	since := set.Duration("since", 0, "Only report the requests sent this long after the start of the run or later (e.g. 2m).")
+	until := set.Duration("until", 0, "Only report the requests sent before this long after the start of the run (e.g. 5m).")
+	status := set.String("status", "", "Only report the requests whose status code is of this class: "+strings.Join(db.StatusClasses, ", ")+", for the network errors.")
+	interval := set.Duration("interval", time.Second, "Width of the intervals the results over time are split in (e.g. 10s).")
+	precision := set.Int("precision", stats.DefaultDigits, "Significant digits the latencies are recorded with, between 1 and 5.")
+	percentiles := set.String("percentiles", "50,90,95,99,99.9,99.99", "Comma separated percentiles of the latencies in the report.")
+	var thresholds stringsFlag
+	set.Var(&thresholds, "threshold", "Pass/fail criterion evaluated against the records, as metric<value (e.g. p99<300ms). Can be repeated. Exits with 2 when one is breached.")
+	var outputs outputFlag
+	set.Var(&outputs, "output", "Report output as format or format:path, format being text or json. Can be repeated. Defaults to text on the console.")
+	set.Parse(args)
+
+	errors := []string{}
+	if set.NArg() > 0 {
+		errors = append(errors, fmt.Sprintf("unexpected arguments %v", set.Args()))
+	}
+	if *from == "" {
+		errors = append(errors, "report needs the results database of the run, with --from")
+	}
+	if *since < 0 || *until < 0 {
+		errors = append(errors, "since and until must not be negative")
+	}
+	if *until > 0 && *until <= *since {
+		errors = append(errors, "until must be greater than since")
+	}
+	if *status != "" && !slices.Contains(db.StatusClasses, *status) {
+		errors = append(errors, fmt.Sprintf("status %q must be one of %s", *status, strings.Join(db.StatusClasses, ", ")))
+	}
+	if *interval <= 0 {
+		errors = append(errors, "interval must be greater than 0")
+	}
+	if *precision < 1 || *precision > 5 {
+		errors = append(errors, "precision must be between 1 and 5 significant digits")
+	}
+	parsed, err := entity.ParsePercentiles(*percentiles)
+	if err != nil {
+		errors = append(errors, err.Error())
+	}
+	q := dto.Report{From: *from, Run: *run, Tags: tags, List: *list, Endpoint: *endpoint, Since: *since, Until: *until, Status: *status, Interval: *interval, Precision: *precision, Percentiles: parsed}
+	for _, pc := range q.Percentiles {
+		if err := entity.CheckPercentile(pc); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	for _, s := range thresholds {
+		t, err := entity.ParseThreshold(s)
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+		q.Thresholds = append(q.Thresholds, t)
+	}
+	if len(outputs) == 0 {
+		outputs = outputFlag{{Format: "text"}}
+	}
+	for _, o := range outputs {
+		if !slices.Contains(plan.Formats, o.Format) {
+			errors = append(errors, fmt.Sprintf("output format %q must be one of %s", o.Format, strings.Join(plan.Formats, ", ")))
+		}
+		q.Outputs = append(q.Outputs, dto.Output{Format: o.Format, Path: o.Path})
+	}
 	if len(errors) > 0 {
-		fmt.Println("Invalid parameters:")
-		for _, err := range errors {
-			fmt.Println(err)
-			slog.Error(err)
+		fail(errors)
+	}
+	return q
+}
+
+// validate checks the plan, adding its problems to the given ones, and sends one request
+// to every target to make sure it answers. It exits listing the problems when there are
+// any, otherwise it returns the plan as the dto.Load to run.
+func validate(p *plan.Plan, errors []string) dto.Load {
+	errors = append(errors, p.Validate()...)
+	if len(errors) == 0 {
+		vars := map[string]string{}
+		for _, f := range p.FeederList() {
+			for k, v := range f.Rows[0] {
+				vars[f.Name+"."+k] = v
+			}
 		}
-		fmt.Println("Usage: go run main.go --url=http://localhost:8080 --requests=1000 --concurrency=10")
-		os.Exit(1)
+		var endpoints []dto.Endpoint
+		for _, e := range p.Endpoints() {
+			endpoints = append(endpoints, entity.RenderEndpoint(e, vars))
+		}
+		for _, j := range p.JourneyList() {
+			e := entity.RenderEndpoint(j.Steps[0], vars)
+			if len(entity.Placeholders(e)) == 0 {
+				endpoints = append(endpoints, e)
+			}
+		}
+		for _, e := range endpoints {
+			var expected []int
+			for _, c := range e.Checks {
+				expected = append(expected, c.Status...)
+			}
+			err := pool.StressEndpoint(e.Method, e.URL, e.Body, e.Header, time.Duration(p.Timeout), expected...)
+			if err != nil {
+				errors = append(errors, fmt.Sprintf("%s %s: %s. Check the URL, method, headers, body and status checks.", e.Method, e.URL, err.Error()))
+			}
+		}
+	}
+	if len(errors) > 0 {
+		fail(errors)
 	}
+	return p.ToLoad()
+}
 
-	return
+// fail prints the given problems and the usage, and exits the program.
+func fail(errors []string) {
+	fmt.Println("Invalid parameters:")
+	for _, err := range errors {
+		fmt.Println(err)
+		slog.Error(err)
+	}
+	fmt.Println("Usage: go run main.go --url=http://localhost:8080 --requests=1000 --concurrency=10")
+	fmt.Println("       go run main.go --url=http://localhost:8080 --duration=15m --concurrency=50")
+	fmt.Println("       go run main.go --url=http://localhost:8080 --duration=1m --rate=500 --max-in-flight=100")
+	fmt.Println("       go run main.go --url=http://localhost:8080 --stages=2m:100,10m:100,1m:0")
+	fmt.Println("       go run main.go --url=http://localhost:8080/orders --method=POST --header=\"Content-Type: application/json\" --body-file=order.json")
+	fmt.Println("       go run main.go --url=http://localhost:8080 --duration=1m --threshold=p99<300ms --threshold=error_rate<1%")
+	fmt.Println("       go run main.go --url=http://localhost:8080 --duration=10m --abort=\"error_rate>50% for 10s\" --abort=\"p95>5s\"")
+	fmt.Println("       go run main.go --url=http://localhost:8080 --duration=5m --out=results.db --git-sha=3f2c1ab --tag=env=staging")
+	fmt.Println("       go run main.go run plan.yaml [--concurrency=20 ...]")
+	fmt.Println("       go run main.go run plans/journey.yaml")
+	fmt.Println("       go run main.go report --from=results.db [--run=id] [--tag=env=staging] [--endpoint=name] [--since=1m] [--until=5m] [--status=5xx]")
+	fmt.Println("       go run main.go report --from=results.db --list [--tag=env=staging]")
+	os.Exit(1)
 }
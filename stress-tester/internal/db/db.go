@@ -3,30 +3,179 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"stress-tester/internal/dto"
+	"stress-tester/internal/stats"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	_ "modernc.org/sqlite"
 )
 
+// redColumns lists the columns of the 'red' table in the order they are scanned into a
+// *dto.Red by queryReds.
+const redColumns = "name, target, intended_at, sent_at, received_at, status_code, duration, stage, failed_check, dns, connect, tls, ttfb, transfer, reused, net_error, error_message, bytes_sent, bytes_received"
+
+// journeyColumns lists the columns of the 'journey' table in the order they are scanned
+// into a *dto.JourneyRed by QueryJourneys.
+const journeyColumns = "name, started_at, finished_at, duration, failed, stage"
+
+// runColumns lists the columns of the 'runs' table in the order they are scanned into a
+// dto.Run by Run.
+const runColumns = "id, started_at, finished_at, targets, load, version, git_sha, tags"
+
+// ErrRunNotFound is returned by Run when the database holds no run with the given id.
+var ErrRunNotFound = errors.New("run not found")
+
+// DB is the ResultStore keeping the records in the 'red' and 'journey' tables of a SQL
+// database. A database kept in a file can hold the records of several runs, each one
+// described in the 'runs' table, see StartRun, and tagged with the id of its run.
 type DB struct {
-	db    *sql.DB
-	input chan *dto.Red
+	db       *sql.DB
+	input    chan *dto.Red
+	settings stats.Settings
+	run      string
 }
 
 // NewDB initializes a new DB instance with the provided SQL database connection
-// and input channel for *dto.Red. It ensures that the 'red' table exists in
-// the database, creating it if necessary. The table includes fields for target,
-// sent_at, received_at, status_code, and duration.
+// and input channel for *dto.Red. It ensures that the 'red', 'journey' and 'runs'
+// tables exist in the database, creating them if necessary.
 
 func NewDB(db *sql.DB, input chan *dto.Red) *DB {
-	db.Exec("CREATE TABLE IF NOT EXISTS red (target text, sent_at timestamp, received_at timestamp, status_code int, duration int)")
+	db.Exec("CREATE TABLE IF NOT EXISTS red (run_id text default '', name text default '', target text, intended_at timestamp, sent_at timestamp, received_at timestamp, status_code int, duration int, stage int default 0, failed_check text default '', dns int default 0, connect int default 0, tls int default 0, ttfb int default 0, transfer int default 0, reused bool default false, net_error text default '', error_message text default '', bytes_sent int default 0, bytes_received int default 0)")
+	db.Exec("CREATE INDEX IF NOT EXISTS red_run_name ON red (run_id, name)")
+	db.Exec("CREATE TABLE IF NOT EXISTS journey (run_id text default '', name text, started_at timestamp, finished_at timestamp, duration int, failed bool, stage int)")
+	db.Exec("CREATE TABLE IF NOT EXISTS runs (id text primary key, started_at timestamp, finished_at timestamp, targets text, load text, version text, git_sha text, tags text)")
 	return &DB{
 		db:    db,
 		input: input,
 	}
 }
 
+// WithSettings sets the settings Aggregate summarizes the records with. It returns the
+// same DB.
+func (d *DB) WithSettings(settings stats.Settings) *DB {
+	d.settings = settings
+	return d
+}
+
+// WithRun selects the run the records are tagged with when appended, and the queries
+// select the records of. It returns the same DB.
+func (d *DB) WithRun(id string) *DB {
+	d.run = id
+	return d
+}
+
+// StartRun inserts the run into the 'runs' table and selects it, see WithRun. Its
+// FinishedAt is left empty until FinishRun is called.
+func (d *DB) StartRun(run dto.Run) error {
+	targets, err := json.Marshal(run.Targets)
+	if err != nil {
+		return err
+	}
+	tags, err := json.Marshal(run.Tags)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec("INSERT INTO runs ("+runColumns+") VALUES (?, ?, NULL, ?, ?, ?, ?, ?)", run.ID, run.StartedAt, string(targets), run.Load, run.Version, run.GitSHA, string(tags))
+	if err != nil {
+		return fmt.Errorf("run %s: %w", run.ID, err)
+	}
+	d.run = run.ID
+	return nil
+}
+
+// FinishRun sets the time the run with the given id finished in the 'runs' table.
+func (d *DB) FinishRun(id string, finishedAt time.Time) error {
+	_, err := d.db.Exec("UPDATE runs SET finished_at = ? WHERE id = ?", finishedAt, id)
+	return err
+}
+
+// Run retrieves the run with the given id from the 'runs' table. It returns
+// ErrRunNotFound when there is none.
+func (d *DB) Run(id string) (dto.Run, error) {
+	runs, err := d.queryRuns("SELECT "+runColumns+" FROM runs WHERE id = ?", id)
+	if err != nil {
+		return dto.Run{}, err
+	}
+	if len(runs) == 0 {
+		return dto.Run{}, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	return runs[0], nil
+}
+
+// Runs retrieves the runs from the 'runs' table that have all of the given tags, in the
+// order they started.
+func (d *DB) Runs(tags []string) ([]dto.Run, error) {
+	query := "SELECT " + runColumns + " FROM runs"
+	var args []any
+	for i, tag := range tags {
+		if i == 0 {
+			query += " WHERE"
+		} else {
+			query += " AND"
+		}
+		query += " EXISTS (SELECT 1 FROM json_each(runs.tags) WHERE value = ?)"
+		args = append(args, tag)
+	}
+	return d.queryRuns(query+" ORDER BY julianday(started_at), id", args...)
+}
+
+// queryRuns executes a query with the given arguments on the 'runs' table and returns the
+// runs it selects. The query must select runColumns.
+func (d *DB) queryRuns(query string, args ...any) ([]dto.Run, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var runs []dto.Run
+	for rows.Next() {
+		run := dto.Run{}
+		var finishedAt sql.NullTime
+		var targets, tags string
+		if err := rows.Scan(&run.ID, &run.StartedAt, &finishedAt, &targets, &run.Load, &run.Version, &run.GitSHA, &tags); err != nil {
+			return nil, err
+		}
+		run.FinishedAt = finishedAt.Time
+		if err := json.Unmarshal([]byte(targets), &run.Targets); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(tags), &run.Tags); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// JourneyNames retrieves the names of the journeys of the records of the run from the
+// 'journey' table, in alphabetical order.
+func (d *DB) JourneyNames() ([]string, error) {
+	return d.queryNames("SELECT DISTINCT name FROM journey WHERE run_id = ? ORDER BY name", d.run)
+}
+
+// queryNames executes a query with the given arguments selecting a single text column and
+// returns its values.
+func (d *DB) queryNames(query string, args ...any) ([]string, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
 // Store takes a context and a channel of *dto.Red. It will consume all available
 // values from the channel and insert them into the 'red' table in the database.
 // It will stop consuming the channel when the context is canceled.
@@ -35,56 +184,228 @@ func (d *DB) Store(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
-		default:
-			r := <-d.input
-			_, err := d.db.Exec("INSERT INTO red (target, sent_at, received_at, status_code, duration) VALUES ( ?, ?, ?, ?, ?)", r.Target, r.SentAt, r.ReceivedAt, r.StatusCode, r.Duration)
-			if err != nil {
+		case r := <-d.input:
+			if err := d.Append(r); err != nil {
 				slog.Error("db.Store", "msg", err.Error())
 			}
 		}
 	}
 }
 
-// getReds executes a query on the 'red' table and returns a slice of *dto.Red
-// representing the results. If an error occurs while executing the query, or
-// while scanning the results, an error is logged and the query continues to the
-// next row.
-func (d *DB) getReds(query string) []*dto.Red {
-	rows, err := d.db.Query(query)
+// insertRed and insertJourney insert a record, tagged with the id of its run, into the
+// 'red' and 'journey' tables, with the arguments returned by redArgs and journeyArgs.
+const (
+	insertRed     = "INSERT INTO red (run_id, " + redColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	insertJourney = "INSERT INTO journey (run_id, " + journeyColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?)"
+)
+
+// redArgs returns the arguments of insertRed for the record of a request.
+func (d *DB) redArgs(r *dto.Red) []any {
+	return []any{d.run, r.Name, r.Target, r.IntendedAt, r.SentAt, r.ReceivedAt, r.StatusCode, r.Duration, r.Stage, r.FailedCheck, r.Phases.DNS, r.Phases.Connect, r.Phases.TLS, r.Phases.TTFB, r.Phases.Transfer, r.Phases.Reused, r.NetError, r.ErrorMessage, r.BytesSent, r.BytesReceived}
+}
+
+// journeyArgs returns the arguments of insertJourney for the record of the run of a
+// journey.
+func (d *DB) journeyArgs(j *dto.JourneyRed) []any {
+	return []any{d.run, j.Name, j.StartedAt, j.FinishedAt, j.Duration, j.Failed, j.Stage}
+}
+
+// Append inserts the record of a request into the 'red' table.
+func (d *DB) Append(r *dto.Red) error {
+	_, err := d.db.Exec(insertRed, d.redArgs(r)...)
+	return err
+}
+
+// AppendJourney inserts the record of the run of a journey into the 'journey' table.
+func (d *DB) AppendJourney(j *dto.JourneyRed) error {
+	_, err := d.db.Exec(insertJourney, d.journeyArgs(j)...)
+	return err
+}
+
+// AppendBatch inserts the records of the requests into the 'red' table and the records of
+// the runs of journeys into the 'journey' table in a single transaction, through prepared
+// statements. Either all of them are inserted or none is.
+func (d *DB) AppendBatch(reds []*dto.Red, journeys []*dto.JourneyRed) error {
+	tx, err := d.db.Begin()
 	if err != nil {
-		slog.Error("db.getReds", "msg", err.Error())
+		return err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
+	if err := insert(tx, insertRed, reds, d.redArgs); err != nil {
+		return err
+	}
+	if err := insert(tx, insertJourney, journeys, d.journeyArgs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insert executes the insert statement within the transaction once for every one of the
+// records, with the arguments returned by args. It does nothing without records.
+func insert[T any](tx *sql.Tx, query string, records []T, args func(T) []any) error {
+	if len(records) == 0 {
+		return nil
+	}
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range records {
+		if _, err := stmt.Exec(args(r)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Query retrieves the records of the run from the 'red' table selected by the filter.
+func (d *DB) Query(f Filter) ([]*dto.Red, error) {
+	where, args := d.where(f)
+	return d.queryReds("SELECT "+redColumns+" FROM red"+where, args...)
+}
+
+// where returns the WHERE clause of a query on the 'red' table selecting the records of
+// the run selected by the filter, and its arguments.
+func (d *DB) where(f Filter) (string, []any) {
+	where, args := f.where()
+	if where == "" {
+		return " WHERE run_id = ?", []any{d.run}
+	}
+	return where + " AND run_id = ?", append(args, d.run)
+}
+
+// QueryJourneys retrieves the records of the run from the 'journey' table for the journey
+// with the given name.
+func (d *DB) QueryJourneys(name string) ([]*dto.JourneyRed, error) {
+	var journeys []*dto.JourneyRed
+	err := d.eachJourney(name, func(j *dto.JourneyRed) { journeys = append(journeys, j) })
+	if err != nil {
+		return nil, err
+	}
+	return journeys, nil
+}
+
+// Aggregate returns the summary of the records of the run from the 'red' table selected by
+// the filter, adding them to the summary one row at a time without keeping them.
+func (d *DB) Aggregate(f Filter) (*stats.Aggregator, error) {
+	a := stats.NewAggregator(d.settings)
+	where, args := d.where(f)
+	if err := d.eachRed(a.Add, "SELECT "+redColumns+" FROM red"+where, args...); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// AggregateJourney returns the summary of the records of the run from the 'journey' table
+// for the journey with the given name, adding them one row at a time.
+func (d *DB) AggregateJourney(name string) (*stats.JourneyAggregator, error) {
+	a := stats.NewJourneyAggregator(d.settings.Digits, d.settings.Percentiles)
+	if err := d.eachJourney(name, a.Add); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Each calls fn with every record of the run from the 'red' table selected by the filter,
+// in the order they were sent, one row at a time.
+func (d *DB) Each(f Filter, fn func(*dto.Red)) error {
+	where, args := d.where(f)
+	return d.eachRed(fn, "SELECT "+redColumns+" FROM red"+where+" ORDER BY julianday(sent_at)", args...)
+}
+
+// LastReceivedAt retrieves the time the last response of the run was received, from the
+// 'red' table, zero when the run has no records.
+func (d *DB) LastReceivedAt() (time.Time, error) {
+	var at time.Time
+	err := d.db.QueryRow("SELECT received_at FROM red WHERE run_id = ? ORDER BY julianday(received_at) DESC LIMIT 1", d.run).Scan(&at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return at, err
+}
+
+// queryReds executes a query with the given arguments on the 'red' table and returns a
+// slice of *dto.Red representing the results. The query must select redColumns. It returns
+// the first error found executing the query or scanning its rows.
+func (d *DB) queryReds(query string, args ...any) ([]*dto.Red, error) {
 	var reds []*dto.Red
+	if err := d.eachRed(func(r *dto.Red) { reds = append(reds, r) }, query, args...); err != nil {
+		return nil, err
+	}
+	return reds, nil
+}
+
+// eachRed executes a query with the given arguments on the 'red' table and calls fn with
+// every row as a *dto.Red, as it is scanned. The query must select redColumns. It returns
+// the first error found executing the query or scanning its rows.
+func (d *DB) eachRed(fn func(*dto.Red), query string, args ...any) error {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 	for rows.Next() {
 		r := &dto.Red{}
-		err := rows.Scan(&r.Target, &r.SentAt, &r.ReceivedAt, &r.StatusCode, &r.Duration)
+		err := rows.Scan(&r.Name, &r.Target, &r.IntendedAt, &r.SentAt, &r.ReceivedAt, &r.StatusCode, &r.Duration, &r.Stage, &r.FailedCheck, &r.Phases.DNS, &r.Phases.Connect, &r.Phases.TLS, &r.Phases.TTFB, &r.Phases.Transfer, &r.Phases.Reused, &r.NetError, &r.ErrorMessage, &r.BytesSent, &r.BytesReceived)
 		if err != nil {
-			slog.Error("db.getReds scan", "msg", err.Error())
+			return err
 		}
-		reds = append(reds, r)
+		fn(r)
+	}
+	return rows.Err()
+}
+
+// eachJourney calls fn with every record of the run from the 'journey' table for the
+// journey with the given name, as it is scanned.
+func (d *DB) eachJourney(name string, fn func(*dto.JourneyRed)) error {
+	rows, err := d.db.Query("SELECT "+journeyColumns+" FROM journey WHERE name = ? AND run_id = ?", name, d.run)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		j := &dto.JourneyRed{}
+		if err := rows.Scan(&j.Name, &j.StartedAt, &j.FinishedAt, &j.Duration, &j.Failed, &j.Stage); err != nil {
+			return err
+		}
+		fn(j)
+	}
+	return rows.Err()
+}
+
+// getReds executes a query with the given arguments on the 'red' table and returns a
+// slice of *dto.Red representing the results. The query must select redColumns. If an
+// error occurs while executing the query or scanning the results, it is logged and no
+// records are returned.
+func (d *DB) getReds(query string, args ...any) []*dto.Red {
+	reds, err := d.queryReds(query, args...)
+	if err != nil {
+		slog.Error("db.getReds", "msg", err.Error())
+		return nil
 	}
 	return reds
 }
 
-// GetAllReds retrieves all records from the 'red' table. It returns a slice of *dto.Red
-// representing these records.
+// GetAllReds retrieves all records of the run from the 'red' table. It returns a slice of
+// *dto.Red representing these records.
 func (d *DB) GetAllReds() []*dto.Red {
-	return d.getReds("SELECT target, sent_at, received_at, status_code, duration FROM red")
+	return d.getReds("SELECT "+redColumns+" FROM red WHERE run_id = ?", d.run)
 }
 
-// GetRedsWithoutErrors retrieves all records from the 'red' table where the status code is 200,
-// indicating that the request was successful. It returns a slice of *dto.Red representing these
-// records.
+// GetRedsWithoutErrors retrieves all records of the run from the 'red' table whose
+// response passed all of its checks, indicating that the request was successful. It
+// returns a slice of *dto.Red representing these records.
 func (d *DB) GetRedsWithoutErrors() []*dto.Red {
-	return d.getReds("SELECT target, sent_at, received_at, status_code, duration FROM red where status_code = 200")
+	return d.getReds("SELECT "+redColumns+" FROM red where failed_check = '' AND run_id = ?", d.run)
 }
 
-// GetRedWithErrors retrieves all records from the 'red' table where the status code is not 200,
-// indicating that an error occurred. It returns a slice of *dto.Red representing these records.
+// GetRedWithErrors retrieves all records of the run from the 'red' table whose response
+// failed one of its checks, indicating that an error occurred. It returns a slice of
+// *dto.Red representing these records.
 
 func (d *DB) GetRedWithErrors() []*dto.Red {
-	return d.getReds("SELECT target, sent_at, received_at, status_code, duration FROM red WHERE status_code != 200")
+	return d.getReds("SELECT "+redColumns+" FROM red WHERE failed_check != '' AND run_id = ?", d.run)
 }
 
 // Close closes the database connection. It will return any error it encounters.
@@ -92,7 +413,7 @@ func (d *DB) Close() error {
 	return d.db.Close()
 }
 
-// ClearDatabase drops the 'red' table from the database. This action removes all 
+// ClearDatabase drops the 'red' table from the database. This action removes all
 // records stored in the 'red' table, effectively clearing its data.
 
 func (d *DB) ClearDatabase() {
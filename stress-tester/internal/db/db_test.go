@@ -3,14 +3,16 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log/slog"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
 	"stress-tester/internal/dto"
 
-	_ "github.com/mattn/go-sqlite3"
+	_ "modernc.org/sqlite"
 )
 
 func TestNewDB(t *testing.T) {
@@ -29,7 +31,7 @@ func TestNewDB(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			d, err := sql.Open("sqlite3", "file::memory:")
+			d, err := sql.Open("sqlite", "file::memory:")
 			if err != nil {
 				slog.Error("test sql.Open", "msg", err.Error())
 			}
@@ -105,7 +107,7 @@ func TestDB_Store(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			d, err := sql.Open("sqlite3", "file::memory:")
+			d, err := sql.Open("sqlite", "file::memory:")
 			if err != nil {
 				slog.Error("test sql.Open", "msg", err.Error())
 			}
@@ -126,7 +128,7 @@ func TestDB_Store(t *testing.T) {
 
 			time.Sleep(1 * time.Second)
 
-			query := "SELECT target, sent_at, received_at, status_code, duration FROM red"
+			query := "SELECT " + redColumns + " FROM red"
 			reds := db.getReds(query)
 			if len(reds) != 1 {
 				t.Errorf("Expected 1 red, got %d", len(reds))
@@ -151,7 +153,7 @@ func TestDB_GetAllReds(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			d, err := sql.Open("sqlite3", "file::memory:")
+			d, err := sql.Open("sqlite", "file::memory:")
 			if err != nil {
 				slog.Error("test sql.Open", "msg", err.Error())
 			}
@@ -167,11 +169,12 @@ func TestDB_GetAllReds(t *testing.T) {
 				Duration:   1000,
 			}
 			s2 := &dto.Red{
-				Target:     "test2",
-				SentAt:     now,
-				ReceivedAt: now,
-				StatusCode: 500,
-				Duration:   1000,
+				Target:      "test2",
+				SentAt:      now,
+				ReceivedAt:  now,
+				StatusCode:  500,
+				FailedCheck: "status 200",
+				Duration:    1000,
 			}
 
 			go func() {
@@ -205,7 +208,7 @@ func TestDB_GetRedsWithoutErrors(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			d, err := sql.Open("sqlite3", "file::memory:")
+			d, err := sql.Open("sqlite", "file::memory:")
 			if err != nil {
 				slog.Error("test sql.Open", "msg", err.Error())
 			}
@@ -221,11 +224,12 @@ func TestDB_GetRedsWithoutErrors(t *testing.T) {
 				Duration:   1000,
 			}
 			s2 := &dto.Red{
-				Target:     "test2",
-				SentAt:     now,
-				ReceivedAt: now,
-				StatusCode: 500,
-				Duration:   1000,
+				Target:      "test2",
+				SentAt:      now,
+				ReceivedAt:  now,
+				StatusCode:  500,
+				FailedCheck: "status 200",
+				Duration:    1000,
 			}
 			db.input <- s
 			db.input <- s2
@@ -255,7 +259,7 @@ func TestDB_GetRedWithErrors(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			d, err := sql.Open("sqlite3", "file::memory:")
+			d, err := sql.Open("sqlite", "file::memory:")
 			if err != nil {
 				slog.Error("test sql.Open", "msg", err.Error())
 			}
@@ -271,11 +275,12 @@ func TestDB_GetRedWithErrors(t *testing.T) {
 				Duration:   1000,
 			}
 			s2 := &dto.Red{
-				Target:     "test2",
-				SentAt:     now,
-				ReceivedAt: now,
-				StatusCode: 500,
-				Duration:   1000,
+				Target:      "test2",
+				SentAt:      now,
+				ReceivedAt:  now,
+				StatusCode:  500,
+				FailedCheck: "status 200",
+				Duration:    1000,
 			}
 			db.input <- s
 			db.input <- s2
@@ -303,7 +308,7 @@ func TestDB_Close(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			d, err := sql.Open("sqlite3", "file::memory:")
+			d, err := sql.Open("sqlite", "file::memory:")
 			if err != nil {
 				slog.Error("test sql.Open", "msg", err.Error())
 			}
@@ -318,3 +323,88 @@ func TestDB_Close(t *testing.T) {
 		})
 	}
 }
+
+func TestDB_Runs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	runs := []dto.Run{
+		{ID: "first", StartedAt: start, FinishedAt: start.Add(time.Minute), Targets: []string{"GET http://localhost/"}, Load: `{"concurrency":10,"requests":2}`, Version: "dev", GitSHA: "3f2c1ab", Tags: []string{"nightly", "env=staging"}},
+		{ID: "second", StartedAt: start.Add(time.Hour), FinishedAt: start.Add(time.Hour + time.Minute), Targets: []string{"POST http://localhost/orders"}, Load: `{"rate":10,"duration":"1m0s"}`, Version: "dev", Tags: []string{}},
+	}
+	// Each run opens the database anew, as a later run of the program would.
+	for i, run := range runs {
+		d, err := sql.Open("sqlite", "file:"+path+"?_time_format=sqlite")
+		if err != nil {
+			t.Fatalf("sql.Open() error = %v", err)
+		}
+		db := NewDB(d, nil)
+		if err := db.StartRun(run); err != nil {
+			t.Fatalf("StartRun() error = %v", err)
+		}
+		for range i + 1 {
+			if err := db.Append(&dto.Red{Name: run.Targets[0], SentAt: run.StartedAt, ReceivedAt: run.StartedAt, StatusCode: 200}); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+		}
+		if err := db.AppendJourney(&dto.JourneyRed{Name: "flow", StartedAt: run.StartedAt, FinishedAt: run.StartedAt}); err != nil {
+			t.Fatalf("AppendJourney() error = %v", err)
+		}
+		if err := db.FinishRun(run.ID, run.FinishedAt); err != nil {
+			t.Fatalf("FinishRun() error = %v", err)
+		}
+		if err := db.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+
+	d, err := sql.Open("sqlite", "file:"+path+"?_time_format=sqlite")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	db := NewDB(d, nil)
+	defer db.Close()
+	for i, want := range runs {
+		got, err := db.Run(want.ID)
+		if err != nil {
+			t.Fatalf("Run(%s) error = %v", want.ID, err)
+		}
+		if !got.StartedAt.Equal(want.StartedAt) || !got.FinishedAt.Equal(want.FinishedAt) {
+			t.Errorf("Run(%s) started at %v, finished at %v, want %v and %v", want.ID, got.StartedAt, got.FinishedAt, want.StartedAt, want.FinishedAt)
+		}
+		got.StartedAt, got.FinishedAt, want.StartedAt, want.FinishedAt = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Run(%s) = %+v, want %+v", want.ID, got, want)
+		}
+		reds, err := db.WithRun(want.ID).Query(Filter{})
+		if err != nil || len(reds) != i+1 {
+			t.Errorf("Query() of run %s = %d records, %v, want %d", want.ID, len(reds), err, i+1)
+		}
+		journeys, err := db.QueryJourneys("flow")
+		if err != nil || len(journeys) != 1 {
+			t.Errorf("QueryJourneys() of run %s = %d records, %v, want 1", want.ID, len(journeys), err)
+		}
+	}
+	for _, tt := range []struct {
+		tags []string
+		want []string
+	}{
+		{tags: nil, want: []string{"first", "second"}},
+		{tags: []string{"nightly"}, want: []string{"first"}},
+		{tags: []string{"nightly", "env=prod"}, want: nil},
+	} {
+		got, err := db.Runs(tt.tags)
+		var ids []string
+		for _, r := range got {
+			ids = append(ids, r.ID)
+		}
+		if err != nil || !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("Runs(%q) = %q, %v, want %q", tt.tags, ids, err, tt.want)
+		}
+	}
+	if names, err := db.WithRun("second").JourneyNames(); err != nil || !reflect.DeepEqual(names, []string{"flow"}) {
+		t.Errorf("JourneyNames() = %q, %v", names, err)
+	}
+	if _, err := db.Run("missing"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("Run(missing) error = %v, want ErrRunNotFound", err)
+	}
+}
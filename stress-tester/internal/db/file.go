@@ -0,0 +1,152 @@
+package db
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"stress-tester/internal/dto"
+	"stress-tester/internal/stats"
+)
+
+// File is a ResultStore that appends the records to a file, one JSON object per line, as
+// in {"red": {...}} or {"journey": {...}}, through a buffer, so that they take no memory
+// during the run and are kept once it is over. The queries read the file back.
+type File struct {
+	mu       sync.Mutex
+	settings stats.Settings
+	path     string
+	file     *os.File
+	buffer   *bufio.Writer
+	encoder  *json.Encoder
+}
+
+// fileRecord is a line of a File, holding either the record of a request or of the run
+// of a journey.
+type fileRecord struct {
+	Red     *dto.Red        `json:"red,omitempty"`
+	Journey *dto.JourneyRed `json:"journey,omitempty"`
+}
+
+// NewFile creates the file at the given path, truncating it when it exists, and returns a
+// store appending to it and summarizing the records with the given settings.
+func NewFile(path string, settings stats.Settings) (*File, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	buffer := bufio.NewWriter(f)
+	return &File{settings: settings, path: path, file: f, buffer: buffer, encoder: json.NewEncoder(buffer)}, nil
+}
+
+// Append writes the record of the request to the file.
+func (f *File) Append(r *dto.Red) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.encoder.Encode(fileRecord{Red: r})
+}
+
+// AppendJourney writes the record of the run of the journey to the file.
+func (f *File) AppendJourney(j *dto.JourneyRed) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.encoder.Encode(fileRecord{Journey: j})
+}
+
+// AppendBatch writes the records of the requests and of the runs of journeys to the file.
+func (f *File) AppendBatch(reds []*dto.Red, journeys []*dto.JourneyRed) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range reds {
+		if err := f.encoder.Encode(fileRecord{Red: r}); err != nil {
+			return err
+		}
+	}
+	for _, j := range journeys {
+		if err := f.encoder.Encode(fileRecord{Journey: j}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read flushes the buffer and calls fn with every record of the file, in the order they
+// were appended.
+func (f *File) read(fn func(fileRecord)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.buffer.Flush(); err != nil {
+		return err
+	}
+	in, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	dec := json.NewDecoder(bufio.NewReader(in))
+	for {
+		var rec fileRecord
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(rec)
+	}
+}
+
+// Query reads the records of the requests selected by the filter back from the file.
+func (f *File) Query(filter Filter) ([]*dto.Red, error) {
+	var reds []*dto.Red
+	err := f.read(func(rec fileRecord) {
+		if rec.Red != nil && filter.Match(rec.Red) {
+			reds = append(reds, rec.Red)
+		}
+	})
+	return reds, err
+}
+
+// QueryJourneys reads the records of the runs of the journey back from the file.
+func (f *File) QueryJourneys(name string) ([]*dto.JourneyRed, error) {
+	var runs []*dto.JourneyRed
+	err := f.read(func(rec fileRecord) {
+		if rec.Journey != nil && rec.Journey.Name == name {
+			runs = append(runs, rec.Journey)
+		}
+	})
+	return runs, err
+}
+
+// Aggregate returns the summary of the records of the requests selected by the filter,
+// read back from the file one at a time.
+func (f *File) Aggregate(filter Filter) (*stats.Aggregator, error) {
+	a := stats.NewAggregator(f.settings)
+	err := f.read(func(rec fileRecord) {
+		if rec.Red != nil && filter.Match(rec.Red) {
+			a.Add(rec.Red)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// AggregateJourney returns the summary of the runs of the journey.
+func (f *File) AggregateJourney(name string) (*stats.JourneyAggregator, error) {
+	runs, err := f.QueryJourneys(name)
+	return aggregateJourney(f.settings, runs, err)
+}
+
+// Close writes what is left in the buffer and closes the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := f.buffer.Flush()
+	return errors.Join(err, f.file.Close())
+}
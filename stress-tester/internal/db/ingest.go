@@ -0,0 +1,176 @@
+package db
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+// DefaultBuffer, DefaultBatch and DefaultFlush are the number of records an Ingest of a
+// run holds between the requests and the store, the number of records it writes at once,
+// and how long it lets an incomplete batch wait before writing it.
+const (
+	DefaultBuffer = 65536
+	DefaultBatch  = 1000
+	DefaultFlush  = 100 * time.Millisecond
+)
+
+// Ingest is the pipeline the records of a run go through on their way to a store, written
+// in batches by a single goroutine so that recording them does not slow the requests
+// down. The zero value is not usable, use NewIngest.
+type Ingest struct {
+	store    ResultStore
+	reds     chan *dto.Red
+	journeys chan *dto.JourneyRed
+	batch    int
+	flush    time.Duration
+	done     chan struct{}
+
+	// mu guards closed: Add and AddJourney hold it for reading while they send, Close for
+	// writing while it closes the channels.
+	mu     sync.RWMutex
+	closed bool
+
+	blocked atomic.Int64
+	waited  atomic.Int64
+	dropped atomic.Int64
+	result  dto.ResultIngest
+}
+
+// NewIngest starts a pipeline writing to the store, with a buffer of the given number of
+// records, writing batches of at most batch records, and writing an incomplete batch once
+// its first record has waited flush.
+func NewIngest(store ResultStore, buffer, batch int, flush time.Duration) *Ingest {
+	i := &Ingest{
+		store:    store,
+		reds:     make(chan *dto.Red, buffer),
+		journeys: make(chan *dto.JourneyRed, buffer),
+		batch:    batch,
+		flush:    flush,
+		done:     make(chan struct{}),
+		result:   dto.ResultIngest{Buffer: buffer},
+	}
+	go i.consume()
+	return i
+}
+
+// Add queues the record of a request. It only waits when the buffer is full, and drops
+// the record once the pipeline is closed.
+func (i *Ingest) Add(r *dto.Red) {
+	send(i, i.reds, r)
+}
+
+// AddJourney queues the record of the run of a journey, the same way as Add.
+func (i *Ingest) AddJourney(j *dto.JourneyRed) {
+	send(i, i.journeys, j)
+}
+
+// send queues the record in the channel of the pipeline, counting the time it waited for
+// room when the channel is full.
+func send[T any](i *Ingest, ch chan T, rec T) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if i.closed {
+		i.dropped.Add(1)
+		return
+	}
+	select {
+	case ch <- rec:
+		return
+	default:
+	}
+	start := time.Now()
+	ch <- rec
+	i.blocked.Add(1)
+	i.waited.Add(int64(time.Since(start)))
+}
+
+// Close stops taking records, waits until all of the queued ones are written and returns
+// how the records made their way to the store. The records added afterwards, by requests
+// still in flight, are dropped. It does not close the store.
+func (i *Ingest) Close() dto.ResultIngest {
+	i.mu.Lock()
+	if !i.closed {
+		i.closed = true
+		close(i.reds)
+		close(i.journeys)
+	}
+	i.mu.Unlock()
+	<-i.done
+	result := i.result
+	result.Blocked = int(i.blocked.Load())
+	result.Waited = time.Duration(i.waited.Load())
+	result.Dropped = int(i.dropped.Load())
+	return result
+}
+
+// consume takes the records from the channels until both are closed and empty, writing
+// them in batches.
+func (i *Ingest) consume() {
+	defer close(i.done)
+	var reds []*dto.Red
+	var journeys []*dto.JourneyRed
+	timer := time.NewTimer(i.flush)
+	timer.Stop()
+	defer timer.Stop()
+	write := func() {
+		timer.Stop()
+		i.write(reds, journeys)
+		reds, journeys = reds[:0], journeys[:0]
+	}
+	added := func() {
+		i.result.MaxQueued = max(i.result.MaxQueued, len(i.reds)+len(i.journeys)+1)
+		n := len(reds) + len(journeys)
+		if n >= i.batch {
+			write()
+		} else if n == 1 {
+			timer.Reset(i.flush)
+		}
+	}
+	// The channels are set to nil once closed and empty, so they are no longer selected.
+	rs, js := i.reds, i.journeys
+	for rs != nil || js != nil {
+		select {
+		case r, ok := <-rs:
+			if !ok {
+				rs = nil
+				continue
+			}
+			reds = append(reds, r)
+			added()
+		case j, ok := <-js:
+			if !ok {
+				js = nil
+				continue
+			}
+			journeys = append(journeys, j)
+			added()
+		case <-timer.C:
+			write()
+		}
+	}
+	write()
+}
+
+// write writes a batch of records to the store, counting them as failed, and logging the
+// error, when the store can not write them.
+func (i *Ingest) write(reds []*dto.Red, journeys []*dto.JourneyRed) {
+	n := len(reds) + len(journeys)
+	if n == 0 {
+		return
+	}
+	start := time.Now()
+	err := i.store.AppendBatch(reds, journeys)
+	i.result.Writing += time.Since(start)
+	i.result.Batches++
+	i.result.MaxBatch = max(i.result.MaxBatch, n)
+	if err != nil {
+		slog.Error("db.Ingest", "msg", err.Error())
+		i.result.Failed += n
+		return
+	}
+	i.result.Records += n
+}
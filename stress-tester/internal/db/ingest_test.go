@@ -0,0 +1,93 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"stress-tester/internal/dto"
+	"stress-tester/internal/stats"
+)
+
+// slowStore is a Memory store that takes its time writing every batch, or fails to.
+type slowStore struct {
+	*Memory
+	delay time.Duration
+	err   error
+}
+
+func (s *slowStore) AppendBatch(reds []*dto.Red, journeys []*dto.JourneyRed) error {
+	time.Sleep(s.delay)
+	if s.err != nil {
+		return s.err
+	}
+	return s.Memory.AppendBatch(reds, journeys)
+}
+
+func TestIngest(t *testing.T) {
+	tests := []struct {
+		name    string
+		buffer  int
+		batch   int
+		flush   time.Duration
+		delay   time.Duration
+		err     error
+		records int
+		// wait is how long to wait before closing, zero to close right away.
+		wait    time.Duration
+		written int
+		want    dto.ResultIngest
+		blocked bool
+	}{
+		{
+			name: "Flushed on close", buffer: 16, batch: 4, flush: time.Hour, records: 10,
+			want: dto.ResultIngest{Records: 10, Batches: 3, MaxBatch: 4, Buffer: 16},
+		},
+		{
+			name: "Flushed after a while", buffer: 16, batch: 100, flush: 10 * time.Millisecond, records: 3, wait: 200 * time.Millisecond, written: 3,
+			want: dto.ResultIngest{Records: 3, Batches: 1, MaxBatch: 3, Buffer: 16},
+		},
+		{
+			name: "Backpressure", buffer: 1, batch: 1, flush: time.Hour, delay: 20 * time.Millisecond, records: 5,
+			want: dto.ResultIngest{Records: 5, Batches: 5, MaxBatch: 1, Buffer: 1}, blocked: true,
+		},
+		{
+			name: "Failed", buffer: 16, batch: 2, flush: time.Hour, err: errors.New("disk full"), records: 3,
+			want: dto.ResultIngest{Failed: 3, Batches: 2, MaxBatch: 2, Buffer: 16},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &slowStore{Memory: NewMemory(stats.Settings{Digits: 3}), delay: tt.delay, err: tt.err}
+			ingest := NewIngest(store, tt.buffer, tt.batch, tt.flush)
+			for range tt.records {
+				ingest.Add(&dto.Red{StatusCode: 200})
+			}
+			if tt.wait > 0 {
+				time.Sleep(tt.wait)
+				if a, _ := store.Aggregate(Filter{}); a.Count() != tt.written {
+					t.Errorf("written before Close() = %d, want %d", a.Count(), tt.written)
+				}
+			}
+			got := ingest.Close()
+			ingest.Add(&dto.Red{StatusCode: 200})
+			if dropped := ingest.Close().Dropped; dropped != 1 {
+				t.Errorf("Dropped after Close() = %d, want 1", dropped)
+			}
+
+			if tt.blocked != (got.Blocked > 0) || tt.blocked != (got.Waited > 0) {
+				t.Errorf("Close() blocked %d requests for %v, want blocked %v", got.Blocked, got.Waited, tt.blocked)
+			}
+			if got.Writing <= 0 || got.MaxQueued <= 0 || got.MaxQueued > tt.buffer+1 {
+				t.Errorf("Close() writing %v, max queued %d", got.Writing, got.MaxQueued)
+			}
+			got.Blocked, got.Waited, got.Writing, got.MaxQueued = 0, 0, 0, 0
+			if got != tt.want {
+				t.Errorf("Close() = %+v, want %+v", got, tt.want)
+			}
+			if a, _ := store.Aggregate(Filter{}); a.Count() != tt.want.Records {
+				t.Errorf("written = %d, want %d", a.Count(), tt.want.Records)
+			}
+		})
+	}
+}
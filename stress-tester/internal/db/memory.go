@@ -0,0 +1,125 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+
+	"stress-tester/internal/dto"
+	"stress-tester/internal/stats"
+)
+
+// Memory is a ResultStore that summarizes the records as they are appended, without
+// keeping them. It aggregates all the records, or those of one endpoint or stage, and its
+// queries return ErrNotKept.
+type Memory struct {
+	mu       sync.Mutex
+	settings stats.Settings
+	all      *stats.Aggregator
+	names    map[string]*stats.Aggregator
+	stages   map[int]*stats.Aggregator
+	journeys map[string]*stats.JourneyAggregator
+}
+
+// NewMemory creates an empty in-memory store summarizing the records with the given
+// settings.
+func NewMemory(settings stats.Settings) *Memory {
+	return &Memory{
+		settings: settings,
+		all:      stats.NewAggregator(settings),
+		names:    map[string]*stats.Aggregator{},
+		stages:   map[int]*stats.Aggregator{},
+		journeys: map[string]*stats.JourneyAggregator{},
+	}
+}
+
+// Append adds the record to the summaries of all the records, of its endpoint and of its
+// stage.
+func (m *Memory) Append(r *dto.Red) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.all.Add(r)
+	if m.names[r.Name] == nil {
+		m.names[r.Name] = stats.NewAggregator(m.settings)
+	}
+	m.names[r.Name].Add(r)
+	if m.stages[r.Stage] == nil {
+		m.stages[r.Stage] = stats.NewAggregator(m.settings)
+	}
+	m.stages[r.Stage].Add(r)
+	return nil
+}
+
+// AppendJourney adds the run to the summary of its journey.
+func (m *Memory) AppendJourney(j *dto.JourneyRed) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.journeys[j.Name] == nil {
+		m.journeys[j.Name] = stats.NewJourneyAggregator(m.settings.Digits, m.settings.Percentiles)
+	}
+	m.journeys[j.Name].Add(j)
+	return nil
+}
+
+// AppendBatch adds the records of the requests and of the runs of journeys to their
+// summaries.
+func (m *Memory) AppendBatch(reds []*dto.Red, journeys []*dto.JourneyRed) error {
+	for _, r := range reds {
+		m.Append(r)
+	}
+	for _, j := range journeys {
+		m.AppendJourney(j)
+	}
+	return nil
+}
+
+// Query returns ErrNotKept.
+func (m *Memory) Query(f Filter) ([]*dto.Red, error) {
+	return nil, ErrNotKept
+}
+
+// QueryJourneys returns ErrNotKept.
+func (m *Memory) QueryJourneys(name string) ([]*dto.JourneyRed, error) {
+	return nil, ErrNotKept
+}
+
+// Aggregate returns the summary of all the records, of the records of an endpoint or of a
+// stage, empty when there are none. It returns an error for a filter selecting both an
+// endpoint and a stage, or selecting the records by time or status.
+func (m *Memory) Aggregate(f Filter) (*stats.Aggregator, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var a *stats.Aggregator
+	switch {
+	case f.Name != "" && f.ByStage:
+		return nil, fmt.Errorf("the memory store can not aggregate by endpoint and stage at once: %w", ErrNotKept)
+	case !f.From.IsZero() || !f.To.IsZero() || f.Status != "":
+		return nil, fmt.Errorf("the memory store can not aggregate by time or status: %w", ErrNotKept)
+	case f.Name != "":
+		a = m.names[f.Name]
+	case f.ByStage:
+		a = m.stages[f.Stage]
+	default:
+		a = m.all
+	}
+	if a == nil {
+		a = stats.NewAggregator(m.settings)
+	}
+	return a, nil
+}
+
+// AggregateJourney returns the summary of the runs of the journey, empty when there are
+// none.
+func (m *Memory) AggregateJourney(name string) (*stats.JourneyAggregator, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a := m.journeys[name]
+	if a == nil {
+		a = stats.NewJourneyAggregator(m.settings.Digits, m.settings.Percentiles)
+	}
+	return a, nil
+}
+
+// Close does nothing, the summaries stay available.
+func (m *Memory) Close() error {
+	return nil
+}
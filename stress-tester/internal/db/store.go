@@ -0,0 +1,137 @@
+package db
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"stress-tester/internal/dto"
+	"stress-tester/internal/stats"
+)
+
+// ErrNotKept is returned by the queries of a store that summarizes the records it is given
+// without keeping them.
+var ErrNotKept = errors.New("the store does not keep the records")
+
+// ResultStore is where the records of a run go as the requests get a response, and where
+// the report takes them from. The stores that do not keep the records return ErrNotKept
+// from Query and QueryJourneys.
+type ResultStore interface {
+	// Append adds the record of a request.
+	Append(r *dto.Red) error
+	// AppendJourney adds the record of a whole run of a journey.
+	AppendJourney(j *dto.JourneyRed) error
+	// AppendBatch adds the records of several requests and runs of journeys at once. The
+	// slices are reused once it returns.
+	AppendBatch(reds []*dto.Red, journeys []*dto.JourneyRed) error
+	// Query returns the records of the requests selected by the filter.
+	Query(f Filter) ([]*dto.Red, error)
+	// QueryJourneys returns the records of the runs of the journey with the given name.
+	QueryJourneys(name string) ([]*dto.JourneyRed, error)
+	// Aggregate returns the summary of the records of the requests selected by the filter.
+	Aggregate(f Filter) (*stats.Aggregator, error)
+	// AggregateJourney returns the summary of the runs of the journey with the given name.
+	AggregateJourney(name string) (*stats.JourneyAggregator, error)
+	// Close releases the resources of the store, writing what it still holds.
+	Close() error
+}
+
+// RunLog is implemented by the stores that keep the metadata of the runs next to their
+// records, so that several runs can share the same store.
+type RunLog interface {
+	// StartRun records the metadata of the run, whose records are appended from then on.
+	StartRun(run dto.Run) error
+	// FinishRun records the time the run with the given id finished.
+	FinishRun(id string, finishedAt time.Time) error
+}
+
+// Filter selects records of requests by Name, Stage when ByStage is set, time sent From
+// and To and Status class, "1xx" to "5xx" or "error". The zero value selects all of them.
+type Filter struct {
+	Name    string
+	Stage   int
+	ByStage bool
+	From    time.Time
+	To      time.Time
+	Status  string
+}
+
+// StatusClasses lists the classes of status codes a Filter can select.
+var StatusClasses = []string{"1xx", "2xx", "3xx", "4xx", "5xx", "error"}
+
+// Match tells whether the filter selects the record.
+func (f Filter) Match(r *dto.Red) bool {
+	if f.Name != "" && r.Name != f.Name {
+		return false
+	}
+	if f.ByStage && r.Stage != f.Stage {
+		return false
+	}
+	if !f.From.IsZero() && r.SentAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !r.SentAt.Before(f.To) {
+		return false
+	}
+	if f.Status != "" {
+		lo, hi := f.statusRange()
+		return r.StatusCode >= lo && r.StatusCode <= hi
+	}
+	return true
+}
+
+// statusRange returns the lowest and highest status codes of the class of the filter.
+// Network errors have a status code of -1.
+func (f Filter) statusRange() (int, int) {
+	class, err := strconv.Atoi(f.Status[:1])
+	if err != nil {
+		return -1, -1
+	}
+	return class * 100, class*100 + 99
+}
+
+// where returns the WHERE clause of a query on the 'red' table selecting the records of
+// the filter, empty when it selects all of them, and its arguments.
+func (f Filter) where() (string, []any) {
+	clause := ""
+	var args []any
+	add := func(condition string, arg any) {
+		if clause == "" {
+			clause = " WHERE " + condition
+		} else {
+			clause += " AND " + condition
+		}
+		args = append(args, arg)
+	}
+	if f.Name != "" {
+		add("name = ?", f.Name)
+	}
+	if f.ByStage {
+		add("stage = ?", f.Stage)
+	}
+	if !f.From.IsZero() {
+		add("julianday(sent_at) >= julianday(?)", f.From)
+	}
+	if !f.To.IsZero() {
+		add("julianday(sent_at) < julianday(?)", f.To)
+	}
+	if f.Status != "" {
+		lo, hi := f.statusRange()
+		add("status_code >= ?", lo)
+		add("status_code <= ?", hi)
+	}
+	return clause, args
+}
+
+// aggregateJourney returns a journey aggregator with the given settings holding the given
+// runs.
+func aggregateJourney(settings stats.Settings, runs []*dto.JourneyRed, err error) (*stats.JourneyAggregator, error) {
+	if err != nil {
+		return nil, err
+	}
+	a := stats.NewJourneyAggregator(settings.Digits, settings.Percentiles)
+	for _, j := range runs {
+		a.Add(j)
+	}
+	return a, nil
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"stress-tester/internal/dto"
+	"stress-tester/internal/stats"
+)
+
+func TestResultStore(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	settings := stats.Settings{Start: start, Width: time.Second, Digits: 3, Percentiles: []float64{50, 99}}
+	red := func(name string, stage int, at time.Duration, status int, d time.Duration) *dto.Red {
+		return &dto.Red{Name: name, Target: "http://localhost/" + name, SentAt: start.Add(at), ReceivedAt: start.Add(at + d), StatusCode: status, Duration: d, Stage: stage}
+	}
+	reds := []*dto.Red{
+		red("products", 0, 0, 200, 10*time.Millisecond),
+		red("products", 1, 1500*time.Millisecond, 500, 30*time.Millisecond),
+		red("cart", 0, 200*time.Millisecond, 200, 20*time.Millisecond),
+		red("cart", 1, 1200*time.Millisecond, -1, time.Second),
+	}
+	runs := []*dto.JourneyRed{
+		{Name: "checkout", StartedAt: start, FinishedAt: start.Add(time.Second), Duration: time.Second},
+		{Name: "checkout", StartedAt: start, FinishedAt: start.Add(2 * time.Second), Duration: 2 * time.Second, Failed: true},
+	}
+	open := func(t *testing.T, kind string) ResultStore {
+		switch kind {
+		case "memory":
+			return NewMemory(settings)
+		case "file":
+			f, err := NewFile(filepath.Join(t.TempDir(), "results.jsonl"), settings)
+			if err != nil {
+				t.Fatalf("NewFile() error = %v", err)
+			}
+			return f
+		default:
+			d, err := sql.Open("sqlite", "file::memory:?_time_format=sqlite")
+			if err != nil {
+				t.Fatalf("sql.Open() error = %v", err)
+			}
+			d.SetMaxOpenConns(1)
+			return NewDB(d, nil).WithSettings(settings)
+		}
+	}
+	filters := []struct {
+		name   string
+		filter Filter
+		want   []*dto.Red
+		// summarized tells whether the memory store can aggregate the records.
+		summarized bool
+	}{
+		{name: "All", filter: Filter{}, want: reds, summarized: true},
+		{name: "By name", filter: Filter{Name: "cart"}, want: reds[2:], summarized: true},
+		{name: "By stage", filter: Filter{Stage: 1, ByStage: true}, want: []*dto.Red{reds[1], reds[3]}, summarized: true},
+		{name: "By stage 0", filter: Filter{ByStage: true}, want: []*dto.Red{reds[0], reds[2]}, summarized: true},
+		{name: "By time", filter: Filter{From: start.Add(200 * time.Millisecond), To: start.Add(1500 * time.Millisecond)}, want: []*dto.Red{reds[2], reds[3]}},
+		{name: "By status", filter: Filter{Status: "2xx"}, want: []*dto.Red{reds[0], reds[2]}},
+		{name: "By network error", filter: Filter{Name: "cart", Status: "error"}, want: reds[3:]},
+	}
+	for _, kind := range []string{"sqlite", "memory", "file"} {
+		t.Run(kind, func(t *testing.T) {
+			store := open(t, kind)
+			defer store.Close()
+			ingest := NewIngest(store, 2, 3, time.Hour)
+			for _, r := range reds {
+				ingest.Add(r)
+			}
+			for _, j := range runs {
+				ingest.AddJourney(j)
+			}
+			if got := ingest.Close(); got.Records != len(reds)+len(runs) || got.Failed != 0 {
+				t.Errorf("Close() = %+v, want %d records", got, len(reds)+len(runs))
+			}
+
+			for _, f := range filters {
+				want := stats.Aggregate(f.want, settings)
+				got, err := store.Aggregate(f.filter)
+				if kind == "memory" && !f.summarized {
+					if !errors.Is(err, ErrNotKept) {
+						t.Errorf("Aggregate(%s) error = %v, want %v", f.name, err, ErrNotKept)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("Aggregate(%s) error = %v", f.name, err)
+				}
+				if got.Count() != want.Count() || !reflect.DeepEqual(got.Red(), want.Red()) || !reflect.DeepEqual(got.Errors(), want.Errors()) || !reflect.DeepEqual(got.Percentiles(), want.Percentiles()) {
+					t.Errorf("Aggregate(%s) = %+v, want %+v", f.name, got.Red(), want.Red())
+				}
+				queried, err := store.Query(f.filter)
+				if kind == "memory" {
+					if !errors.Is(err, ErrNotKept) {
+						t.Errorf("Query(%s) error = %v, want %v", f.name, err, ErrNotKept)
+					}
+					continue
+				}
+				if err != nil || len(queried) != len(f.want) {
+					t.Errorf("Query(%s) = %d records, %v, want %d", f.name, len(queried), err, len(f.want))
+				}
+			}
+			journey, err := store.AggregateJourney("checkout")
+			if err != nil {
+				t.Fatalf("AggregateJourney() error = %v", err)
+			}
+			if got := journey.Result(); got.Runs != 2 || got.Failed != 1 || got.MaxDuration != 2*time.Second {
+				t.Errorf("AggregateJourney() = %+v", got)
+			}
+		})
+	}
+}
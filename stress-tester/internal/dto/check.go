@@ -0,0 +1,24 @@
+package dto
+
+import "time"
+
+// Check is an expectation about the responses of an endpoint. Type is one of status,
+// body_contains, body_regex, json, header or max_latency, which use Status, Expr and
+// Equals, or MaxLatency to tell what the response must hold.
+type Check struct {
+	Name       string
+	Type       string
+	Status     []int
+	Expr       string
+	Equals     string
+	MaxLatency time.Duration
+}
+
+// ResultCheck holds how many responses of the endpoint passed and failed one of its
+// checks.
+type ResultCheck struct {
+	Endpoint string
+	Check    string
+	Passed   int
+	Failed   int
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "net/http"
+
+// Endpoint describes the request sent to the target: its method, url, headers and body.
+// Name identifies the endpoint in the report and Weight is its share of the traffic,
+// relative to the weights of the other endpoints. Checks are the expectations its
+// responses must meet to be a success. Extract is only used by the steps of a journey.
+type Endpoint struct {
+	Name    string
+	Weight  int
+	Method  string
+	URL     string
+	Header  http.Header
+	Body    string
+	Checks  []Check
+	Extract []Extractor
+}
@@ -0,0 +1,10 @@
+package dto
+
+// Feeder is a list of rows, read from a CSV or JSONL file, whose columns are used as
+// {{Name.column}} in the url, headers and body of the requests. Mode tells how the row of
+// each request is picked: "sequential", "random" or "unique".
+type Feeder struct {
+	Name string
+	Mode string
+	Rows []map[string]string
+}
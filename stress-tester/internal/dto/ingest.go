@@ -0,0 +1,19 @@
+package dto
+
+import "time"
+
+// ResultIngest tells how the records of a run made their way to the store: the batches
+// written, how full the buffer got, how long requests Waited for room in it, and the
+// records that Failed to be written or were Dropped after the buffer was closed.
+type ResultIngest struct {
+	Records   int
+	Batches   int
+	MaxBatch  int
+	Writing   time.Duration
+	Buffer    int
+	MaxQueued int
+	Blocked   int
+	Waited    time.Duration
+	Failed    int
+	Dropped   int
+}
@@ -0,0 +1,33 @@
+package dto
+
+import "time"
+
+// Journey is an ordered flow of requests run by a virtual user, like logging in and then
+// using the token of the login in the next requests. Weight is its share of the traffic,
+// relative to the weights of the other journeys.
+type Journey struct {
+	Name   string
+	Weight int
+	Steps  []Endpoint
+}
+
+// Extractor takes a value out of a response and stores it in the variable Name, to be
+// used by the next steps of a journey as {{Name}}. From is "json", "regex" or "header"
+// and Expr is, respectively, a JSONPath, a regular expression whose first group (or whole
+// match) is taken, or a header name.
+type Extractor struct {
+	Name string
+	From string
+	Expr string
+}
+
+// JourneyRed is the record of one run of a journey, from the start of its first step to
+// the end of its last one. Failed tells whether a step failed, stopping the journey.
+type JourneyRed struct {
+	Name       string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Duration   time.Duration
+	Failed     bool
+	Stage      int
+}
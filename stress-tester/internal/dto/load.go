@@ -0,0 +1,31 @@
+package dto
+
+import "time"
+
+// Load describes how the requests of a test run are sent to the target: Concurrency
+// workers at a time, a constant Rate of requests per second, or workers following the
+// Stages. The run stops early when one of the Aborts holds, the requests in flight then
+// getting at most Grace to finish, and passes when all of the Thresholds pass.
+type Load struct {
+	Endpoints   []Endpoint
+	Journeys    []Journey
+	Feeders     []Feeder
+	Requests    int
+	Concurrency int
+	Duration    time.Duration
+	Rate        float64
+	MaxInFlight int
+	Stages      []Stage
+	Timeout     time.Duration
+	Thresholds  []Threshold
+	Aborts      []Abort
+	Grace       time.Duration
+	Interval    time.Duration
+	Precision   int
+	Percentiles []float64
+	Outputs     []Output
+	Store       Store
+	Version     string
+	GitSHA      string
+	Tags        []string
+}
@@ -0,0 +1,25 @@
+package dto
+
+// NetError is the category of the error of a request that got no response. A request
+// that could not even be built, as when its url does not parse, is an invalid request.
+type NetError string
+
+const (
+	NetErrorTimeout           NetError = "timeout"
+	NetErrorConnectionRefused NetError = "connection_refused"
+	NetErrorConnectionReset   NetError = "connection_reset"
+	NetErrorDNS               NetError = "dns"
+	NetErrorTLS               NetError = "tls"
+	NetErrorEOF               NetError = "eof"
+	NetErrorCanceled          NetError = "canceled"
+	NetErrorInvalidRequest    NetError = "invalid_request"
+	NetErrorOther             NetError = "other"
+)
+
+// ResultNetError holds how many requests got no response because of an error of one
+// category, along with the message of one of those errors as a sample.
+type ResultNetError struct {
+	Category NetError
+	Requests int
+	Sample   string
+}
@@ -0,0 +1,8 @@
+package dto
+
+// Output is a format the report is written in, "text" or "json", and the file it is
+// written to. An empty Path writes to the console.
+type Output struct {
+	Format string
+	Path   string
+}
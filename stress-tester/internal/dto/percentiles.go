@@ -2,11 +2,19 @@ package dto
 
 import "time"
 
+// Percentiles holds the distribution of the durations of a set of requests: the duration
+// at each of the percentiles asked for, in the order they were asked for, along with the
+// shortest, longest, mean and standard deviation of the durations.
 type Percentiles struct {
-	P10 time.Duration
-	P25 time.Duration
-	P50 time.Duration
-	P75 time.Duration
-	P90 time.Duration
-	P99 time.Duration
+	Values []Percentile
+	Min    time.Duration
+	Max    time.Duration
+	Mean   time.Duration
+	StdDev time.Duration
+}
+
+// Percentile holds the duration below which Percentile percent of the durations fall.
+type Percentile struct {
+	Percentile float64
+	Duration   time.Duration
 }
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+// Phases holds how long each phase of a request took, as traced by net/http/httptrace.
+// DNS, Connect and TLS are zero when the request Reused a connection.
+type Phases struct {
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	TTFB     time.Duration
+	Transfer time.Duration
+	Reused   bool
+}
+
+// ResultPhases holds the distribution of the durations of each phase of the requests of a
+// test run that got a response, along with how many of them reused a connection.
+type ResultPhases struct {
+	Requests int
+	Reused   int
+	Phases   []ResultPhase
+}
+
+// ResultPhase holds the distribution of the durations of one phase, over the Requests that
+// went through it.
+type ResultPhase struct {
+	Phase       string
+	Requests    int
+	Percentiles Percentiles
+}
@@ -2,10 +2,22 @@ package dto
 
 import "time"
 
+// Red is the record of one request. FailedCheck is the first check its response failed,
+// empty when it passed all of them, and StatusCode is -1 on a network error, NetError
+// then being its category. IntendedAt is when the request was meant to be sent.
 type Red struct {
-	Target     string
-	SentAt     time.Time
-	ReceivedAt time.Time
-	StatusCode int
-	Duration   time.Duration
+	Name          string
+	Target        string
+	IntendedAt    time.Time
+	SentAt        time.Time
+	ReceivedAt    time.Time
+	StatusCode    int
+	Duration      time.Duration
+	Stage         int
+	FailedCheck   string
+	Phases        Phases
+	NetError      NetError
+	ErrorMessage  string
+	BytesSent     int64
+	BytesReceived int64
 }
@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// Report describes the report of a run kept in the results database From, written again
+// from its records. Run picks the run, the last one with all of the Tags when empty, and
+// Endpoint, Since, Until and Status narrow the records down, see db.Filter.
+type Report struct {
+	From        string
+	Run         string
+	Tags        []string
+	List        bool
+	Endpoint    string
+	Since       time.Duration
+	Until       time.Duration
+	Status      string
+	Interval    time.Duration
+	Precision   int
+	Percentiles []float64
+	Thresholds  []Threshold
+	Outputs     []Output
+}
@@ -2,11 +2,22 @@ package dto
 
 import "time"
 
+// ResultRed holds the results of the requests sent during one interval of a test run,
+// From being the offset of its start from the start of the run and Width its length, up to
+// the last response for the last interval.
+// AverageDuration is the sum of the durations of the requests that got a response, to be
+// divided by their number. BytesSent and BytesReceived are the sizes of the requests and
+// of their responses, headers and body.
 type ResultRed struct {
+	From                         time.Duration
+	Width                        time.Duration
 	NumRequestPerSecond          int
 	NumRequestWithErrorPerSecond int
 	NumNetworkErrorPerSecond     int
 	AverageDuration              time.Duration
 	MaxDuration                  time.Duration
 	MinDuration                  time.Duration
+	Percentiles                  Percentiles
+	BytesSent                    int64
+	BytesReceived                int64
 }
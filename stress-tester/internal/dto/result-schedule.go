@@ -0,0 +1,10 @@
+package dto
+
+type ResultSchedule struct {
+	Rate        float64
+	MaxInFlight int
+	Scheduled   int
+	Sent        int
+	Late        int
+	Dropped     int
+}
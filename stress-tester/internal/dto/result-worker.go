@@ -0,0 +1,9 @@
+package dto
+
+import "time"
+
+type ResultWorker struct {
+	ID       int
+	Requests int
+	Busy     time.Duration
+}
@@ -0,0 +1,17 @@
+package dto
+
+import "time"
+
+// Run is the metadata of a test run kept in a results database next to its records, so it
+// can be reported again later. FinishedAt is zero while the run is going on.
+type Run struct {
+	ID         string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Targets    []string
+	Load       string
+	Version    string
+	GitSHA     string
+	Tags       []string
+	Path       string
+}
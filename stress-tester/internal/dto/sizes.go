@@ -0,0 +1,17 @@
+package dto
+
+// Sizes holds the distribution of the sizes of a set of responses, headers and body, in
+// bytes: the size at each of the percentiles asked for, in the order they were asked
+// for, along with the smallest, largest and mean size.
+type Sizes struct {
+	Values []Size
+	Min    int64
+	Max    int64
+	Mean   int64
+}
+
+// Size holds the size below which Percentile percent of the sizes fall.
+type Size struct {
+	Percentile float64
+	Bytes      int64
+}
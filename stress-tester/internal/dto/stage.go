@@ -0,0 +1,10 @@
+package dto
+
+import "time"
+
+// Stage is one step of a load profile: over Duration the number of active workers moves
+// linearly from the target of the previous stage (zero for the first one) to Target.
+type Stage struct {
+	Duration time.Duration
+	Target   int
+}
@@ -0,0 +1,10 @@
+package dto
+
+// Store is where the records of a run are kept for the report: "sqlite", the default, a
+// SQLite database, in memory or, when Path is set, in that file, where the records and
+// the metadata of the run outlive it, "memory", which only keeps the summaries of the
+// records, or "file", which appends them to the file at Path, one JSON object per line.
+type Store struct {
+	Kind string
+	Path string
+}
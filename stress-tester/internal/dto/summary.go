@@ -0,0 +1,68 @@
+package dto
+
+import "time"
+
+// Summary holds everything the report of a test run shows, so it can be written in any
+// of the output formats. Stopped is why the run stopped early, empty when it ran to the
+// end, and Canceled the requests in flight when the grace ran out, left out of the
+// results.
+type Summary struct {
+	Target      string
+	Requests    int
+	Canceled    int
+	Elapsed     time.Duration
+	Stopped     string
+	Interrupted bool
+	Workers     []ResultWorker
+	Schedule    *ResultSchedule
+	Stages      []ResultStage
+	Endpoints   []ResultEndpoint
+	Journeys    []ResultJourney
+	Checks      []ResultCheck
+	Thresholds  []ResultThreshold
+	Red         map[string]*ResultRed
+	Errors      map[int]*ResultError
+	NetErrors   map[NetError]*ResultNetError
+	Percentiles Percentiles
+	Corrected   Percentiles
+	Phases      ResultPhases
+	Sizes       Sizes
+	Run         *Run
+	Ingest      *ResultIngest
+}
+
+// ResultEndpoint holds the results of the requests sent to one endpoint of a traffic mix,
+// or to one step of a journey. Share is the share of the requests, between 0 and 1, the
+// endpoint was expected to get, zero for the steps of a journey. Sizes holds the
+// distribution of the sizes of its responses, to spot the truncated ones.
+type ResultEndpoint struct {
+	Endpoint    Endpoint
+	Share       float64
+	Red         map[string]*ResultRed
+	Errors      map[int]*ResultError
+	NetErrors   map[NetError]*ResultNetError
+	Percentiles Percentiles
+	Sizes       Sizes
+}
+
+// ResultJourney holds the results of the whole runs of one journey. Share is the share of
+// the runs, between 0 and 1, the journey was expected to get.
+type ResultJourney struct {
+	Name            string
+	Share           float64
+	Runs            int
+	Failed          int
+	AverageDuration time.Duration
+	MinDuration     time.Duration
+	MaxDuration     time.Duration
+	Percentiles     Percentiles
+}
+
+// ResultStage holds the results of the requests sent during one stage of a load profile.
+// Red is empty when no request was sent during the stage.
+type ResultStage struct {
+	Stage       Stage
+	From        int
+	Red         map[string]*ResultRed
+	Percentiles Percentiles
+}
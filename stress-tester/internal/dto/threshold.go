@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+// Threshold is a pass/fail criterion of a test run, like "p99<300ms", comparing Metric
+// with Value using Op. Metric is pNN, avg, min, max, error_rate, rps, requests, errors,
+// status_NNN or status_Nxx, the last two as a share between 0 and 1 when Rate is set.
+type Threshold struct {
+	Text       string
+	Metric     string
+	Op         string
+	Value      float64
+	Rate       bool
+	Percentile float64
+}
+
+// ResultThreshold holds the value a threshold was compared with and whether it passed.
+type ResultThreshold struct {
+	Threshold string
+	Actual    string
+	Passed    bool
+}
+
+// Abort is a condition that stops a test run early, like "error_rate>50% for 10s". The run
+// stops as soon as Condition holds for the requests that got a response in the last
+// Window of the run, a latency condition also counting the requests still waiting for
+// longer than its value.
+type Abort struct {
+	Text      string
+	Condition Threshold
+	Window    time.Duration
+}
@@ -0,0 +1,155 @@
+package entity
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"stress-tester/internal/dto"
+)
+
+// Check verifies an expectation about a response, see dto.Check, counting how many
+// responses passed and failed it.
+type Check struct {
+	Name   string
+	check  dto.Check
+	regexp *regexp.Regexp
+	path   *JSONPath
+	passed atomic.Int64
+	failed atomic.Int64
+}
+
+// NewCheck checks and compiles the given check. A check without a name is named after
+// what it verifies, as in "status 200|201" or "latency <= 300ms".
+func NewCheck(c dto.Check) (*Check, error) {
+	check := &Check{Name: c.Name, check: c}
+	var err error
+	name := ""
+	switch c.Type {
+	case "status":
+		if len(c.Status) == 0 {
+			err = fmt.Errorf("status must not be empty")
+		}
+		codes := make([]string, len(c.Status))
+		for i, s := range c.Status {
+			codes[i] = fmt.Sprint(s)
+		}
+		name = "status " + strings.Join(codes, "|")
+	case "body_contains":
+		name = fmt.Sprintf("body contains %q", c.Expr)
+	case "body_regex":
+		check.regexp, err = regexp.Compile(c.Expr)
+		name = "body matches " + c.Expr
+	case "json":
+		check.path, err = ParseJSONPath(c.Expr)
+		name = c.Expr + " exists"
+		if c.Equals != "" {
+			name = c.Expr + " == " + c.Equals
+		}
+	case "header":
+		if c.Expr == "" {
+			err = fmt.Errorf("header name must not be empty")
+		}
+		name = "header " + c.Expr
+	case "max_latency":
+		if c.MaxLatency <= 0 {
+			err = fmt.Errorf("max latency must be greater than 0")
+		}
+		name = fmt.Sprint("latency <= ", c.MaxLatency)
+	default:
+		err = fmt.Errorf("type %q must be status, body_contains, body_regex, json, header or max_latency", c.Type)
+	}
+	if check.Name == "" {
+		check.Name = name
+	}
+	if err != nil {
+		return nil, fmt.Errorf("check %s: %w", check.Name, err)
+	}
+	return check, nil
+}
+
+// Verify tells whether the response of the sent Red passes the check, counting the
+// result.
+func (c *Check) Verify(r *Red) bool {
+	ok := c.verify(r)
+	if ok {
+		c.passed.Add(1)
+	} else {
+		c.failed.Add(1)
+	}
+	return ok
+}
+
+func (c *Check) verify(r *Red) bool {
+	switch c.check.Type {
+	case "status":
+		return slices.Contains(c.check.Status, r.StatusCode)
+	case "body_contains":
+		return bytes.Contains(r.Body, []byte(c.check.Expr))
+	case "body_regex":
+		return c.regexp.Match(r.Body)
+	case "json":
+		v, err := c.path.FindString(r.Body)
+		return err == nil && (c.check.Equals == "" || v == c.check.Equals)
+	case "header":
+		return r.ResponseHeader.Get(c.check.Expr) != ""
+	default:
+		return r.StatusCode != -1 && r.ReceivedAt.Sub(r.SentAt) <= c.check.MaxLatency
+	}
+}
+
+// Result returns how many responses passed and failed the check.
+func (c *Check) Result() (passed, failed int) {
+	return int(c.passed.Load()), int(c.failed.Load())
+}
+
+// Checks are the checks of an endpoint.
+type Checks []*Check
+
+// NewChecks compiles the checks of the endpoint. An endpoint without a status check gets
+// the default "status 200" check, so that a response is only a success when it answers
+// with a 200, as when the endpoint has no checks at all.
+func NewChecks(e dto.Endpoint) (Checks, error) {
+	var checks Checks
+	status := false
+	for _, c := range e.Checks {
+		check, err := NewCheck(c)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+		status = status || c.Type == "status"
+	}
+	if !status {
+		check, _ := NewCheck(dto.Check{Type: "status", Status: []int{200}})
+		checks = append(Checks{check}, checks...)
+	}
+	return checks, nil
+}
+
+// Capture tells whether the checks need the body or headers of the response.
+func (cs Checks) Capture() bool {
+	for _, c := range cs {
+		switch c.check.Type {
+		case "body_contains", "body_regex", "json", "header":
+			return true
+		}
+	}
+	return false
+}
+
+// Verify runs every check against the response of the sent Red and sets its FailedCheck
+// to the name of the first one it failed, empty when it passed all of them. It tells
+// whether the response passed all the checks.
+func (cs Checks) Verify(r *Red) bool {
+	r.FailedCheck = ""
+	for _, c := range cs {
+		if !c.Verify(r) && r.FailedCheck == "" {
+			r.FailedCheck = c.Name
+		}
+	}
+	return r.FailedCheck == ""
+}
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+func TestChecks_Verify(t *testing.T) {
+	now := time.Now()
+	r := &Red{
+		SentAt:         now,
+		ReceivedAt:     now.Add(200 * time.Millisecond),
+		StatusCode:     201,
+		Body:           []byte(`{"data":{"id":42,"name":"ana"}}`),
+		ResponseHeader: http.Header{"X-Request-Id": {"abc"}},
+	}
+	tests := []struct {
+		name  string
+		check dto.Check
+		want  bool
+	}{
+		{name: "status in set", check: dto.Check{Type: "status", Status: []int{200, 201}}, want: true},
+		{name: "status not in set", check: dto.Check{Type: "status", Status: []int{200}}},
+		{name: "body contains", check: dto.Check{Type: "body_contains", Expr: `"name":"ana"`}, want: true},
+		{name: "body does not contain", check: dto.Check{Type: "body_contains", Expr: "bruno"}},
+		{name: "body regex", check: dto.Check{Type: "body_regex", Expr: `"id":\d+`}, want: true},
+		{name: "body regex no match", check: dto.Check{Type: "body_regex", Expr: `^\[`}},
+		{name: "json exists", check: dto.Check{Type: "json", Expr: "$.data.name"}, want: true},
+		{name: "json equals", check: dto.Check{Type: "json", Expr: "$.data.id", Equals: "42"}, want: true},
+		{name: "json not equal", check: dto.Check{Type: "json", Expr: "$.data.id", Equals: "41"}},
+		{name: "json missing", check: dto.Check{Type: "json", Expr: "$.data.email"}},
+		{name: "header present", check: dto.Check{Type: "header", Expr: "x-request-id"}, want: true},
+		{name: "header missing", check: dto.Check{Type: "header", Expr: "X-Trace"}},
+		{name: "fast enough", check: dto.Check{Type: "max_latency", MaxLatency: 300 * time.Millisecond}, want: true},
+		{name: "too slow", check: dto.Check{Type: "max_latency", MaxLatency: 100 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks, err := NewChecks(dto.Endpoint{Checks: []dto.Check{{Type: "status", Status: []int{201}}, tt.check}})
+			if err != nil {
+				t.Fatalf("NewChecks() error = %v", err)
+			}
+			if got := checks.Verify(r); got != tt.want {
+				t.Errorf("Checks.Verify() = %v, want %v", got, tt.want)
+			}
+			if tt.want != (r.FailedCheck == "") {
+				t.Errorf("Red.FailedCheck = %q", r.FailedCheck)
+			}
+			passed, failed := checks[1].Result()
+			if tt.want && (passed != 1 || failed != 0) || !tt.want && (passed != 0 || failed != 1) {
+				t.Errorf("Check.Result() = %d, %d", passed, failed)
+			}
+		})
+	}
+}
+
+func TestNewChecks(t *testing.T) {
+	checks, err := NewChecks(dto.Endpoint{Checks: []dto.Check{{Type: "max_latency", MaxLatency: time.Second}}})
+	if err != nil {
+		t.Fatalf("NewChecks() error = %v", err)
+	}
+	if len(checks) != 2 || checks[0].Name != "status 200" || checks[1].Name != "latency <= 1s" {
+		t.Errorf("NewChecks() = %v, want the default status check first", checks)
+	}
+	if r := (&Red{StatusCode: 500}); checks[:1].Verify(r) || r.FailedCheck != "status 200" {
+		t.Errorf("Checks.Verify() of a 500 passed, FailedCheck = %q", r.FailedCheck)
+	}
+
+	for _, c := range []dto.Check{
+		{Type: "status"},
+		{Type: "body_regex", Expr: "("},
+		{Type: "json", Expr: "data"},
+		{Type: "header"},
+		{Type: "max_latency"},
+		{Type: "xml"},
+	} {
+		if _, err := NewChecks(dto.Endpoint{Checks: []dto.Check{c}}); err == nil {
+			t.Errorf("NewChecks(%v) error = nil, want an error", c)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"stress-tester/internal/dto"
+)
+
+type Extractor struct {
+	Name   string
+	From   string
+	Expr   string
+	path   *JSONPath
+	regexp *regexp.Regexp
+}
+
+// NewExtractor checks and compiles the given extractor.
+func NewExtractor(x dto.Extractor) (*Extractor, error) {
+	e := &Extractor{Name: x.Name, From: x.From, Expr: x.Expr}
+	if x.Name == "" {
+		return nil, fmt.Errorf("extractor name must not be empty")
+	}
+	var err error
+	switch x.From {
+	case "json":
+		e.path, err = ParseJSONPath(x.Expr)
+	case "regex":
+		e.regexp, err = regexp.Compile(x.Expr)
+	case "header":
+		if x.Expr == "" {
+			err = fmt.Errorf("header name must not be empty")
+		}
+	default:
+		err = fmt.Errorf("from %q must be json, regex or header", x.From)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("extractor %s: %w", x.Name, err)
+	}
+	return e, nil
+}
+
+// Extract returns the value the extractor takes from a response with the given body and
+// headers. It returns an error when the value is not found.
+func (e *Extractor) Extract(body []byte, header http.Header) (string, error) {
+	switch e.From {
+	case "json":
+		v, err := e.path.FindString(body)
+		if err != nil {
+			return "", fmt.Errorf("extractor %s: %w", e.Name, err)
+		}
+		return v, nil
+	case "regex":
+		m := e.regexp.FindSubmatch(body)
+		if m == nil {
+			return "", fmt.Errorf("extractor %s: regex %q does not match", e.Name, e.Expr)
+		}
+		if len(m) > 1 {
+			return string(m[1]), nil
+		}
+		return string(m[0]), nil
+	default:
+		v := header.Get(e.Expr)
+		if v == "" {
+			return "", fmt.Errorf("extractor %s: header %q not found", e.Name, e.Expr)
+		}
+		return v, nil
+	}
+}
@@ -0,0 +1,156 @@
+package entity
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"stress-tester/internal/dto"
+)
+
+// ErrFeederExhausted is returned by Feeder.Next when a unique feeder has handed out all of
+// its rows.
+var ErrFeederExhausted = errors.New("ran out of rows")
+
+// Feeder hands out the rows of a dto.Feeder, one per request or per run of a journey,
+// each column of the row as the variable "name.column". A unique feeder fails with
+// ErrFeederExhausted once every row was handed out.
+type Feeder struct {
+	Name string
+	Mode string
+	rows []map[string]string
+	next atomic.Int64
+}
+
+// NewFeeder creates the feeder for the given rows, prefixing their columns with the name
+// of the feeder. The feeder must have at least one row.
+func NewFeeder(f dto.Feeder) (*Feeder, error) {
+	if len(f.Rows) == 0 {
+		return nil, fmt.Errorf("feeder %s: no rows", f.Name)
+	}
+	switch f.Mode {
+	case "sequential", "random", "unique":
+	default:
+		return nil, fmt.Errorf("feeder %s: mode %q must be sequential, random or unique", f.Name, f.Mode)
+	}
+	feeder := &Feeder{Name: f.Name, Mode: f.Mode, rows: make([]map[string]string, len(f.Rows))}
+	for i, row := range f.Rows {
+		feeder.rows[i] = make(map[string]string, len(row))
+		for k, v := range row {
+			feeder.rows[i][f.Name+"."+k] = v
+		}
+	}
+	return feeder, nil
+}
+
+// Next returns the variables of the next row. It is safe for concurrent use. The returned
+// map must not be changed.
+func (f *Feeder) Next() (map[string]string, error) {
+	switch f.Mode {
+	case "random":
+		return f.rows[rand.IntN(len(f.rows))], nil
+	case "unique":
+		n := f.next.Add(1) - 1
+		if n >= int64(len(f.rows)) {
+			return nil, fmt.Errorf("feeder %s %w after %d requests", f.Name, ErrFeederExhausted, len(f.rows))
+		}
+		return f.rows[n], nil
+	default:
+		n := f.next.Add(1) - 1
+		return f.rows[n%int64(len(f.rows))], nil
+	}
+}
+
+// UsedBy tells whether any of the endpoints uses a column of the feeder.
+func (f *Feeder) UsedBy(endpoints ...dto.Endpoint) bool {
+	for _, e := range endpoints {
+		for _, name := range Placeholders(e) {
+			if strings.HasPrefix(name, f.Name+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadFeeder reads the rows of a feeder file. Files ending in .jsonl or .ndjson hold one
+// JSON object per line, whose values are taken as they are when they are strings and as
+// JSON otherwise, null being an empty string. Anything else is read as CSV, its first line
+// naming the columns.
+func ReadFeeder(path string) ([]map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		rows, err = readJSONL(b)
+	default:
+		rows, err = readCSV(b)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: no rows", path)
+	}
+	return rows, nil
+}
+
+// readCSV reads CSV records into rows keyed by the names in the first record.
+func readCSV(b []byte) ([]map[string]string, error) {
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			row[strings.TrimSpace(name)] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readJSONL reads one JSON object per line, skipping blank lines.
+func readJSONL(b []byte) ([]map[string]string, error) {
+	var rows []map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 64*1024), len(b)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(text, &object); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		row := make(map[string]string, len(object))
+		for k, raw := range object {
+			var s string
+			if json.Unmarshal(raw, &s) == nil {
+				row[k] = s
+			} else {
+				row[k] = string(raw)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
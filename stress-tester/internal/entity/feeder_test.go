@@ -0,0 +1,123 @@
+package entity
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"stress-tester/internal/dto"
+)
+
+func TestReadFeeder(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "CSV",
+			file:    "users.csv",
+			content: "id, email\n1,a@example.com\n2,\"b,c@example.com\"\n",
+			want:    []map[string]string{{"id": "1", "email": "a@example.com"}, {"id": "2", "email": "b,c@example.com"}},
+		},
+		{
+			name:    "JSONL",
+			file:    "search.jsonl",
+			content: "{\"term\":\"shoes\",\"page\":2,\"filters\":{\"size\":[41,42]}}\n\n{\"term\":\"bags\",\"page\":null}\n",
+			want:    []map[string]string{{"term": "shoes", "page": "2", "filters": `{"size":[41,42]}`}, {"term": "bags", "page": ""}},
+		},
+		{
+			name:    "Header only",
+			file:    "empty.csv",
+			content: "id,email\n",
+			wantErr: true,
+		},
+		{
+			name:    "Bad CSV",
+			file:    "bad.csv",
+			content: "id,email\n1\n",
+			wantErr: true,
+		},
+		{
+			name:    "Bad JSONL",
+			file:    "bad.ndjson",
+			content: "{\"term\":\"shoes\"}\n[1,2]\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadFeeder(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadFeeder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadFeeder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeeder_Next(t *testing.T) {
+	rows := []map[string]string{{"id": "1"}, {"id": "2"}, {"id": "3"}}
+	tests := []struct {
+		mode    string
+		want    []string
+		wantErr bool
+	}{
+		{mode: "sequential", want: []string{"1", "2", "3", "1", "2"}},
+		{mode: "unique", want: []string{"1", "2", "3"}, wantErr: true},
+		{mode: "random"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			f, err := NewFeeder(dto.Feeder{Name: "users", Mode: tt.mode, Rows: rows})
+			if err != nil {
+				t.Fatalf("NewFeeder() error = %v", err)
+			}
+			var got []string
+			for range 5 {
+				row, err := f.Next()
+				if err != nil {
+					if !tt.wantErr || !errors.Is(err, ErrFeederExhausted) {
+						t.Fatalf("Feeder.Next() error = %v, wantErr %v", err, tt.wantErr)
+					}
+					break
+				}
+				if tt.mode == "random" {
+					if _, ok := row["users.id"]; !ok {
+						t.Fatalf("Feeder.Next() = %v, want a users.id", row)
+					}
+					continue
+				}
+				got = append(got, row["users.id"])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Feeder.Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewFeeder(dto.Feeder{Name: "users", Mode: "sequential"}); err == nil {
+		t.Errorf("NewFeeder() without rows error = nil, want an error")
+	}
+}
+
+func TestFeeder_UsedBy(t *testing.T) {
+	f, _ := NewFeeder(dto.Feeder{Name: "users", Mode: "sequential", Rows: []map[string]string{{"id": "1"}}})
+	if !f.UsedBy(dto.Endpoint{URL: "http://localhost/users/{{ users.id }}"}) {
+		t.Errorf("Feeder.UsedBy() = false, want true")
+	}
+	if f.UsedBy(dto.Endpoint{URL: "http://localhost/users/{{id}}", Body: "{{usersx.id}}"}) {
+		t.Errorf("Feeder.UsedBy() = true, want false")
+	}
+}
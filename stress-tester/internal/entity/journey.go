@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"stress-tester/internal/dto"
+)
+
+// Step is one request of a journey, the checks of its response and the extractors taking
+// values out of it.
+type Step struct {
+	Endpoint   dto.Endpoint
+	Checks     Checks
+	Extractors []*Extractor
+}
+
+type Journey struct {
+	Name  string
+	Steps []Step
+}
+
+// NewJourney checks the given journey and compiles the checks and extractors of its
+// steps.
+func NewJourney(j dto.Journey) (*Journey, error) {
+	journey := &Journey{Name: j.Name}
+	for _, e := range j.Steps {
+		checks, err := NewChecks(e)
+		if err != nil {
+			return nil, fmt.Errorf("journey %s, step %s: %w", j.Name, e.Name, err)
+		}
+		step := Step{Endpoint: e, Checks: checks}
+		for _, x := range e.Extract {
+			extractor, err := NewExtractor(x)
+			if err != nil {
+				return nil, fmt.Errorf("journey %s, step %s: %w", j.Name, e.Name, err)
+			}
+			step.Extractors = append(step.Extractors, extractor)
+		}
+		journey.Steps = append(journey.Steps, step)
+	}
+	return journey, nil
+}
+
+// Run runs the steps of the journey in order, rendered with the given variables and the
+// ones extracted from the steps before, calling record after each step. It returns why
+// the journey stopped, nil when every step succeeded.
+func (j *Journey) Run(ctx context.Context, client *http.Client, given map[string]string, record func(step dto.Endpoint, r *Red)) error {
+	vars := make(map[string]string, len(given))
+	for k, v := range given {
+		vars[k] = v
+	}
+	for _, s := range j.Steps {
+		e := RenderEndpoint(s.Endpoint, vars)
+		r := &Red{
+			Method:  e.Method,
+			Target:  e.URL,
+			Header:  e.Header,
+			Payload: e.Body,
+			Capture: len(s.Extractors) > 0 || s.Checks.Capture(),
+		}
+		r.Do(ctx, client)
+		ok := s.Checks.Verify(r)
+		record(e, r)
+		if r.NetError != "" {
+			return fmt.Errorf("step %s: %s: %s", e.Name, r.NetError, r.ErrorMessage)
+		}
+		if !ok {
+			return fmt.Errorf("step %s: check %s failed, status code %d", e.Name, r.FailedCheck, r.StatusCode)
+		}
+		for _, x := range s.Extractors {
+			v, err := x.Extract(r.Body, r.ResponseHeader)
+			if err != nil {
+				return fmt.Errorf("step %s: %w", e.Name, err)
+			}
+			vars[x.Name] = v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"stress-tester/internal/dto"
+	"stress-tester/internal/pool"
+)
+
+func TestJourney_Run(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Session", "s1")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"token":"abc"},"user":"id=42;"}`)
+	})
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" || r.Header.Get("X-Session") != "s1" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, "user %s", r.PathValue("id"))
+	})
+	mux.HandleFunc("GET /odd", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"50%off","token":"a\nb","next":"http://%zz"}`))
+	})
+	mux.HandleFunc("GET /coupons/{code}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("code") != "50%off" {
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	odd := func(name, expr string) dto.Endpoint {
+		return dto.Endpoint{Name: "odd/odd", Method: "GET", URL: server.URL + "/odd", Extract: []dto.Extractor{{Name: name, From: "json", Expr: expr}}}
+	}
+	login := dto.Endpoint{
+		Name:   "flow/login",
+		Method: "POST",
+		URL:    server.URL + "/login",
+		Extract: []dto.Extractor{
+			{Name: "token", From: "json", Expr: "$.data.token"},
+			{Name: "session", From: "header", Expr: "X-Session"},
+			{Name: "id", From: "regex", Expr: `id=(\d+)`},
+		},
+	}
+	user := dto.Endpoint{
+		Name:   "flow/user",
+		Method: "GET",
+		URL:    server.URL + "/users/{{id}}",
+		Header: http.Header{"Authorization": {"Bearer {{ token }}"}, "X-Session": {"{{session}}"}},
+	}
+	tests := []struct {
+		name      string
+		journey   dto.Journey
+		wantSteps []string
+		wantErr   bool
+	}{
+		{
+			name:      "Success",
+			journey:   dto.Journey{Name: "flow", Steps: []dto.Endpoint{login, user}},
+			wantSteps: []string{"POST " + server.URL + "/login", "GET " + server.URL + "/users/42"},
+		},
+		{
+			name: "Failed extractor",
+			journey: dto.Journey{Name: "flow", Steps: []dto.Endpoint{
+				{Name: "flow/login", Method: "POST", URL: server.URL + "/login", Extract: []dto.Extractor{{Name: "token", From: "json", Expr: "$.token"}}},
+				user,
+			}},
+			wantSteps: []string{"POST " + server.URL + "/login"},
+			wantErr:   true,
+		},
+		{
+			name:      "Failed step",
+			journey:   dto.Journey{Name: "flow", Steps: []dto.Endpoint{user, login}},
+			wantSteps: []string{"GET " + server.URL + "/users/{{id}}"},
+			wantErr:   true,
+		},
+		{
+			name: "Escaped value",
+			journey: dto.Journey{Name: "odd", Steps: []dto.Endpoint{
+				odd("code", "$.code"),
+				{Name: "odd/coupon", Method: "GET", URL: server.URL + "/coupons/{{code}}"},
+			}},
+			wantSteps: []string{"GET " + server.URL + "/odd", "GET " + server.URL + "/coupons/50%25off"},
+		},
+		{
+			name: "Invalid url",
+			journey: dto.Journey{Name: "odd", Steps: []dto.Endpoint{
+				odd("next", "$.next"),
+				{Name: "odd/next", Method: "GET", URL: "{{next}}/orders"},
+			}},
+			wantSteps: []string{"GET " + server.URL + "/odd", "GET http://%zz/orders"},
+			wantErr:   true,
+		},
+		{
+			name: "Invalid header",
+			journey: dto.Journey{Name: "odd", Steps: []dto.Endpoint{
+				odd("token", "$.token"),
+				user,
+			}},
+			wantSteps: []string{"GET " + server.URL + "/odd", "GET " + server.URL + "/users/{{id}}"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, err := NewJourney(tt.journey)
+			if err != nil {
+				t.Fatalf("NewJourney() error = %v", err)
+			}
+			var steps []string
+			err = j.Run(context.Background(), pool.GetHttpClient(), nil, func(step dto.Endpoint, r *Red) {
+				steps = append(steps, r.Method+" "+r.Target)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Journey.Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(steps, tt.wantSteps) {
+				t.Errorf("Journey.Run() steps = %v, want %v", steps, tt.wantSteps)
+			}
+		})
+	}
+}
+
+func TestNewJourney(t *testing.T) {
+	tests := []struct {
+		name      string
+		extractor dto.Extractor
+	}{
+		{name: "No name", extractor: dto.Extractor{From: "json", Expr: "$.a"}},
+		{name: "Bad from", extractor: dto.Extractor{Name: "a", From: "xml", Expr: "/a"}},
+		{name: "Bad jsonpath", extractor: dto.Extractor{Name: "a", From: "json", Expr: "a"}},
+		{name: "Bad regex", extractor: dto.Extractor{Name: "a", From: "regex", Expr: "("}},
+		{name: "No header", extractor: dto.Extractor{Name: "a", From: "header"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewJourney(dto.Journey{Name: "flow", Steps: []dto.Endpoint{{Name: "flow/a", Extract: []dto.Extractor{tt.extractor}}}})
+			if err == nil {
+				t.Errorf("NewJourney() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	vars := map[string]string{"token": "abc", "id": "42"}
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "Bearer {{token}}", want: "Bearer abc"},
+		{s: "/users/{{ id }}/orders/{{id}}", want: "/users/42/orders/42"},
+		{s: "{{missing}}", want: "{{missing}}"},
+		{s: "no placeholders", want: "no placeholders"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.s, vars); got != tt.want {
+			t.Errorf("Render(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestRenderURL(t *testing.T) {
+	vars := map[string]string{"base": "http://localhost:8080", "code": "50%off", "q": "a&b c", "name": "a/b\nc"}
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "http://localhost/coupons/{{code}}", want: "http://localhost/coupons/50%25off"},
+		{s: "http://localhost/search?q={{q}}&code={{code}}", want: "http://localhost/search?q=a%26b+c&code=50%25off"},
+		{s: "http://localhost/users/{{name}}#{{q}}", want: "http://localhost/users/a%2Fb%0Ac#a%26b+c"},
+		{s: "{{base}}/coupons/{{code}}", want: "http://localhost:8080/coupons/50%25off"},
+		{s: "http://localhost/{{missing}}", want: "http://localhost/{{missing}}"},
+	}
+	for _, tt := range tests {
+		if got := RenderURL(tt.s, vars); got != tt.want {
+			t.Errorf("RenderURL(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
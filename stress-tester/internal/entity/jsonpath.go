@@ -0,0 +1,108 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a parsed JSONPath expression. Only the subset needed to pick a single value
+// is supported: the root $ followed by .key, ['key'] and [index] selectors, negative
+// indexes counting from the end of the array, as in $.data.items[0]['id'].
+type JSONPath struct {
+	Expr     string
+	segments []any
+}
+
+// ParseJSONPath parses a JSONPath expression.
+func ParseJSONPath(expr string) (*JSONPath, error) {
+	p := &JSONPath{Expr: expr}
+	s := strings.TrimSpace(expr)
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("jsonpath %q must start with $", expr)
+	}
+	s = s[1:]
+	for s != "" {
+		switch {
+		case s[0] == '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end == -1 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("jsonpath %q has an empty key", expr)
+			}
+			p.segments = append(p.segments, s[:end])
+			s = s[end:]
+		case s[0] == '[':
+			end := strings.IndexByte(s, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("jsonpath %q has an unclosed [", expr)
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				p.segments = append(p.segments, inner[1:len(inner)-1])
+				continue
+			}
+			i, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("jsonpath %q: [%s] must be an index or a quoted key", expr, inner)
+			}
+			p.segments = append(p.segments, i)
+		default:
+			return nil, fmt.Errorf("jsonpath %q: unexpected %q", expr, s[0])
+		}
+	}
+	return p, nil
+}
+
+// Find returns the value the path points to in the given JSON document.
+func (p *JSONPath) Find(body []byte) (any, error) {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("jsonpath %q: %w", p.Expr, err)
+	}
+	for _, seg := range p.segments {
+		switch seg := seg.(type) {
+		case string:
+			obj, ok := doc.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("jsonpath %q: %q is not in an object", p.Expr, seg)
+			}
+			if doc, ok = obj[seg]; !ok {
+				return nil, fmt.Errorf("jsonpath %q: %q not found", p.Expr, seg)
+			}
+		case int:
+			arr, ok := doc.([]any)
+			if !ok {
+				return nil, fmt.Errorf("jsonpath %q: [%d] is not in an array", p.Expr, seg)
+			}
+			i := seg
+			if i < 0 {
+				i += len(arr)
+			}
+			if i < 0 || i >= len(arr) {
+				return nil, fmt.Errorf("jsonpath %q: [%d] out of range", p.Expr, seg)
+			}
+			doc = arr[i]
+		}
+	}
+	return doc, nil
+}
+
+// FindString returns the value the path points to in the given JSON document as a string:
+// strings as they are, other values encoded as JSON.
+func (p *JSONPath) FindString(body []byte) (string, error) {
+	v, err := p.Find(body)
+	if err != nil {
+		return "", err
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
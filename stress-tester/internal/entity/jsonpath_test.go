@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"testing"
+)
+
+func TestJSONPath_FindString(t *testing.T) {
+	body := []byte(`{"token":"abc","user":{"id":42,"roles":["admin","dev"]},"items":[{"id":"x"},{"id":"y"}],"ok":true,"odd key":1}`)
+	tests := []struct {
+		name     string
+		expr     string
+		want     string
+		wantErr  bool
+		parseErr bool
+	}{
+		{name: "Key", expr: "$.token", want: "abc"},
+		{name: "Nested number", expr: "$.user.id", want: "42"},
+		{name: "Index", expr: "$.user.roles[1]", want: "dev"},
+		{name: "Negative index", expr: "$.items[-1].id", want: "y"},
+		{name: "Quoted key", expr: "$['odd key']", want: "1"},
+		{name: "Bool", expr: "$.ok", want: "true"},
+		{name: "Object", expr: "$.items[0]", want: `{"id":"x"}`},
+		{name: "Root", expr: "$", want: `{"items":[{"id":"x"},{"id":"y"}],"odd key":1,"ok":true,"token":"abc","user":{"id":42,"roles":["admin","dev"]}}`},
+		{name: "Missing key", expr: "$.nope", wantErr: true},
+		{name: "Out of range", expr: "$.items[5]", wantErr: true},
+		{name: "Not an array", expr: "$.token[0]", wantErr: true},
+		{name: "No root", expr: "token", parseErr: true},
+		{name: "Unclosed", expr: "$.items[0", parseErr: true},
+		{name: "Bad index", expr: "$.items[a]", parseErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseJSONPath(tt.expr)
+			if (err != nil) != tt.parseErr {
+				t.Fatalf("ParseJSONPath() error = %v, parseErr %v", err, tt.parseErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := p.FindString(body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("JSONPath.FindString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("JSONPath.FindString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"math/rand/v2"
+	"sort"
+)
+
+// Mix picks one of a list of weighted choices at random, in proportion to their weights.
+// Weights of zero or less count as a weight of one.
+type Mix struct {
+	Weights    []int
+	cumulative []int
+	total      int
+}
+
+// NewMix creates a Mix for the given weights, which must not be empty.
+func NewMix(weights []int) *Mix {
+	m := &Mix{Weights: weights, cumulative: make([]int, len(weights))}
+	for i, w := range weights {
+		m.total += max(w, 1)
+		m.cumulative[i] = m.total
+	}
+	return m
+}
+
+// Pick returns the index of the next choice. It is safe for concurrent use.
+func (m *Mix) Pick() int {
+	return m.pick(rand.IntN(m.total))
+}
+
+// pick returns the index of the choice whose share of the total weight holds n, n being
+// in [0, total).
+func (m *Mix) pick(n int) int {
+	return sort.SearchInts(m.cumulative, n+1)
+}
+
+// Share returns the share of the picks, between 0 and 1, the choice at index i is
+// expected to get.
+func (m *Mix) Share(i int) float64 {
+	return float64(max(m.Weights[i], 1)) / float64(m.total)
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMix_pick(t *testing.T) {
+	m := NewMix([]int{70, 20, 10})
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{n: 0, want: 0},
+		{n: 69, want: 0},
+		{n: 70, want: 1},
+		{n: 89, want: 1},
+		{n: 90, want: 2},
+		{n: 99, want: 2},
+	}
+	for _, tt := range tests {
+		if got := m.pick(tt.n); got != tt.want {
+			t.Errorf("Mix.pick(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestMix_Pick(t *testing.T) {
+	m := NewMix([]int{70, 20, 10})
+	counts := make([]int, 3)
+	const n = 100000
+	for range n {
+		counts[m.Pick()]++
+	}
+	for i := range counts {
+		got := float64(counts[i]) / n
+		if math.Abs(got-m.Share(i)) > 0.01 {
+			t.Errorf("Mix.Pick() share of %d = %v, want %v", i, got, m.Share(i))
+		}
+	}
+}
+
+func TestMix_Share(t *testing.T) {
+	m := NewMix([]int{0, 3})
+	if got := m.Share(0); got != 0.25 {
+		t.Errorf("Mix.Share(0) = %v, want %v", got, 0.25)
+	}
+	if got := m.Share(1); got != 0.75 {
+		t.Errorf("Mix.Share(1) = %v, want %v", got, 0.75)
+	}
+}
@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"stress-tester/internal/dto"
+)
+
+// ClassifyError returns the category of an error of the http client, the one a request
+// that got no response failed with. Errors the client gave up on, as when the timeout
+// of the client ran out, are timeouts, however far the request got.
+func ClassifyError(err error) dto.NetError {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return dto.NetErrorCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return dto.NetErrorTimeout
+	case errors.As(err, &dnsErr):
+		return dto.NetErrorDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return dto.NetErrorConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return dto.NetErrorConnectionReset
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &verifyErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr),
+		strings.Contains(err.Error(), "tls: "):
+		return dto.NetErrorTLS
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return dto.NetErrorEOF
+	}
+	return dto.NetErrorOther
+}
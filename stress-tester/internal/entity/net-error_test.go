@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+func TestClassifyError(t *testing.T) {
+	hangUp := func(reset bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			if reset {
+				conn.(*net.TCPConn).SetLinger(0)
+			}
+			conn.Close()
+		}))
+	}
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer slow.Close()
+	eof, reset := hangUp(false), hangUp(true)
+	defer eof.Close()
+	defer reset.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer secure.Close()
+	closed := httptest.NewServer(nil)
+	closed.Close()
+
+	send := func(target string, timeout time.Duration) error {
+		client := &http.Client{Timeout: timeout, Transport: &http.Transport{}}
+		res, err := client.Get(target)
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+	tests := []struct {
+		name string
+		err  error
+		want dto.NetError
+	}{
+		{name: "Timeout", err: send(slow.URL, 10*time.Millisecond), want: dto.NetErrorTimeout},
+		{name: "Connection refused", err: send(closed.URL, 0), want: dto.NetErrorConnectionRefused},
+		{name: "Connection reset", err: send(reset.URL, 0), want: dto.NetErrorConnectionReset},
+		{name: "EOF", err: send(eof.URL, 0), want: dto.NetErrorEOF},
+		{name: "TLS", err: send(secure.URL, 0), want: dto.NetErrorTLS},
+		{name: "DNS", err: &url.Error{Op: "Get", URL: "http://missing.invalid", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "missing.invalid", IsNotFound: true}}}, want: dto.NetErrorDNS},
+		{name: "Canceled", err: &url.Error{Op: "Get", URL: "http://localhost", Err: context.Canceled}, want: dto.NetErrorCanceled},
+		{name: "Other", err: errors.New("unsupported protocol scheme"), want: dto.NetErrorOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil {
+				t.Fatalf("request error = nil")
+			}
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParsePercentiles parses a comma separated list of percentiles, like
+// "50,90,95,99,99.9,99.99", each one greater than 0 and at most 100.
+func ParsePercentiles(s string) ([]float64, error) {
+	var percentiles []float64
+	for _, part := range strings.Split(s, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("percentile %q is not a number", part)
+		}
+		if err := CheckPercentile(p); err != nil {
+			return nil, err
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, nil
+}
+
+// CheckPercentile returns an error when the percentile is not greater than 0 and at most
+// 100.
+func CheckPercentile(p float64) error {
+	if p <= 0 || p > 100 {
+		return fmt.Errorf("percentile %v must be greater than 0 and at most 100", p)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePercentiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []float64
+		wantErr bool
+	}{
+		{
+			name: "Success",
+			s:    "50, 90,99.9,99.99,100",
+			want: []float64{50, 90, 99.9, 99.99, 100},
+		},
+		{
+			name:    "Not a number",
+			s:       "50,p99",
+			wantErr: true,
+		},
+		{
+			name:    "Zero",
+			s:       "0,50",
+			wantErr: true,
+		},
+		{
+			name:    "Over 100",
+			s:       "100.1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePercentiles(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePercentiles() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePercentiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
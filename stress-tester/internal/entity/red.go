@@ -1,50 +1,130 @@
 package entity
 
 import (
+	"context"
+	"fmt"
 	"io"
-	"log/slog"
 	"net/http"
+	"net/http/httptrace"
+	"strings"
 	"time"
+
+	"stress-tester/internal/dto"
 )
 
+// Red is a request sent to a target and the rate, errors and duration of its response.
+// When Capture is set the body and headers of the response are kept in Body and
+// ResponseHeader, otherwise the body is discarded as it is read.
 type Red struct {
-	Target     string
-	SentAt     time.Time
-	ReceivedAt time.Time
-	StatusCode int
-	Payload    string
+	Method         string
+	Target         string
+	Header         http.Header
+	SentAt         time.Time
+	ReceivedAt     time.Time
+	StatusCode     int
+	Payload        string
+	Capture        bool
+	Body           []byte
+	ResponseHeader http.Header
+	FailedCheck    string
+	Phases         dto.Phases
+	NetError       dto.NetError
+	ErrorMessage   string
+	BytesSent      int64
+	BytesReceived  int64
+}
+
+// counter is an io.Writer that counts the bytes written to it and discards them.
+type counter int64
+
+func (c *counter) Write(p []byte) (int, error) {
+	*c += counter(len(p))
+	return len(p), nil
+}
+
+// requestSize returns the size of the request with the given payload as body, request
+// line, headers and body, as it is written on an HTTP/1.1 connection. The request is
+// written without its context, so that it does not fire the hooks of its trace.
+func requestSize(req *http.Request, payload string) int64 {
+	clone := req.Clone(context.Background())
+	clone.Body = io.NopCloser(strings.NewReader(payload))
+	var c counter
+	clone.Write(&c)
+	return int64(c)
+}
+
+// responseSize returns the size of the status line and headers of the response.
+func responseSize(res *http.Response) int64 {
+	var c counter
+	fmt.Fprintf(&c, "%s %s\r\n", res.Proto, res.Status)
+	res.Header.Write(&c)
+	c.Write([]byte("\r\n"))
+	return int64(c)
 }
 
 // Get sends a GET request to the url in Target and populates the rest of the
-// fields in the Red object. It returns the same object.
-//
-// If an error occurs while sending the request, the error is logged and the
-// function will panic.
-//
-// If an error occurs while reading the response, the error is logged and the
-// function will return the object with the ReceivedAt set to the current time and
-// the StatusCode set to -1.
-func (r *Red) Get(client *http.Client) *Red {
-	req, err := http.NewRequest("GET", r.Target, nil)
+// fields in the Red object. It returns the same object. See Do.
+func (r *Red) Get(ctx context.Context, client *http.Client) *Red {
+	r.Method = http.MethodGet
+	return r.Do(ctx, client)
+}
+
+// Do sends a request with the Method, Header and Payload of the Red object to the url
+// in Target and populates the rest of the fields in the Red object. An empty Method
+// sends a GET. It returns the same object, with the StatusCode set to -1 and the error
+// classified in NetError when the request could not be sent or read, see ClassifyError.
+func (r *Red) Do(ctx context.Context, client *http.Client) *Red {
+	var body io.Reader
+	if r.Payload != "" {
+		body = strings.NewReader(r.Payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.Target, body)
 	if err != nil {
-		slog.Error("(*Red).Get", "msg", err.Error())
-		panic(err)
+		r.SentAt = time.Now()
+		r.ReceivedAt = r.SentAt
+		r.StatusCode = -1
+		r.NetError = dto.NetErrorInvalidRequest
+		r.ErrorMessage = err.Error()
+		return r
+	}
+	t := &trace{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), t.clientTrace()))
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
+	if host := r.Header.Get("Host"); host != "" {
+		req.Host = host
 	}
+	r.BytesSent = requestSize(req, r.Payload)
 	r.SentAt = time.Now()
 
 	res, err := client.Do(req)
 	if err != nil {
 		r.ReceivedAt = time.Now()
 		r.StatusCode = -1
+		r.NetError = ClassifyError(err)
+		r.ErrorMessage = err.Error()
+		r.Phases = t.result(r.SentAt, r.ReceivedAt)
 		return r
 	}
-	_, err = io.Copy(io.Discard, res.Body)
-	if err != nil {
-		slog.Error("(*Red).Get io.Copy", "msg", err.Error())
+	var n int64
+	if r.Capture {
+		r.Body, err = io.ReadAll(res.Body)
+		r.ResponseHeader = res.Header
+		n = int64(len(r.Body))
+	} else {
+		n, err = io.Copy(io.Discard, res.Body)
 	}
+	r.BytesReceived = responseSize(res) + n
 	res.Body.Close()
 
 	r.ReceivedAt = time.Now()
 	r.StatusCode = res.StatusCode
+	if err != nil {
+		r.StatusCode = -1
+		r.NetError = ClassifyError(err)
+		r.ErrorMessage = err.Error()
+	}
+	r.Phases = t.result(r.SentAt, r.ReceivedAt)
 	return r
 }
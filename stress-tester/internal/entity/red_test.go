@@ -1,10 +1,17 @@
 package entity
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"stress-tester/internal/dto"
 	"stress-tester/internal/pool"
 )
 
@@ -21,7 +28,7 @@ func TestRed_Get(t *testing.T) {
 		{
 			name: "Success",
 			r: &Red{
-				Target:     "http://localhost:8080/hello",
+				Target:     "/hello",
 				StatusCode: -1,
 				SentAt:     time.Now(),
 				ReceivedAt: time.Now(),
@@ -30,87 +37,257 @@ func TestRed_Get(t *testing.T) {
 				client: pool.GetHttpClient(),
 			},
 			want: &Red{
-				Target:     "http://localhost:8080/hello",
+				Target:     "/hello",
 				StatusCode: 200,
 				SentAt:     time.Now(),
 				ReceivedAt: time.Now(),
 			},
 		},
 	}
+	mux := http.NewServeMux()
+	mux.Handle("/hello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		w.Write([]byte("Hello, World!"))
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
 	for _, tt := range tests {
-		server := &http.Server{Addr: ":8080"}
-		defer server.Close()
-		http.Handle("/hello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(200)
-			w.Write([]byte("Hello, World!"))
-		}))
-		go server.ListenAndServe()
-
+		tt.r.Target = server.URL + tt.r.Target
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.r.Get(tt.args.client); got.StatusCode != tt.want.StatusCode {
+			if got := tt.r.Get(context.Background(), tt.args.client); got.StatusCode != tt.want.StatusCode {
 				t.Errorf("Red.Get() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-// func TestRed_Post(t *testing.T) {
-// 	type args struct {
-// 		client *http.Client
-// 	}
-// 	tests := []struct {
-// 		name string
-// 		r    *Red
-// 		args args
-// 		want *Red
-// 	}{
-// 		{
-// 			name: "Success",
-// 			r: &Red{
-// 				Target:     "http://localhost:8080/hello",
-// 				StatusCode: -1,
-// 				SentAt:     time.Now(),
-// 				ReceivedAt: time.Now(),
-// 				Payload:    `{"message":"World"}`,
-// 			},
-// 			args: args{
-// 				client: pool.GetHttpClient(),
-// 			},
-// 			want: &Red{
-// 				Target:     "http://localhost:8080/hello",
-// 				StatusCode: 200,
-// 				SentAt:     time.Now(),
-// 				ReceivedAt: time.Now(),
-// 			},
-// 		},
-// 	}
-// 	for _, tt := range tests {
-// 		server := &http.Server{Addr: ":8080"}
-// 		defer server.Close()
-// 		http.HandleFunc("POST /hello", func(w http.ResponseWriter, r *http.Request) {
-// 			body, err := io.ReadAll(r.Body)
-// 			if err != nil {
-// 				slog.Error("Can't read body", "error", err)
-// 				http.Error(w, "Can't read body", http.StatusInternalServerError)
-// 				return
-// 			}
-// 			var message struct {
-// 				Message string `json:"message"`
-// 			}
-// 			err = json.Unmarshal(body, &message)
-// 			if err != nil {
-// 				slog.Error("Can't unmarshal json", "error", err)
-// 				http.Error(w, "Can't unmarshal json", http.StatusInternalServerError)
-// 				return
-// 			}
-// 			fmt.Fprintf(w, "Hello, %s!", message.Message)
-// 		})
-// 		go server.ListenAndServe()
+func TestRed_Do(t *testing.T) {
+	type args struct {
+		client *http.Client
+	}
+	tests := []struct {
+		name string
+		r    *Red
+		args args
+		want *Red
+	}{
+		{
+			name: "Success",
+			r: &Red{
+				Method:     "POST",
+				Target:     "/hello",
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				StatusCode: -1,
+				SentAt:     time.Now(),
+				ReceivedAt: time.Now(),
+				Payload:    `{"message":"World"}`,
+			},
+			args: args{
+				client: pool.GetHttpClient(),
+			},
+			want: &Red{
+				StatusCode: 200,
+			},
+		},
+		{
+			name: "Missing header",
+			r: &Red{
+				Method:  "POST",
+				Target:  "/hello",
+				Payload: `{"message":"World"}`,
+			},
+			args: args{
+				client: pool.GetHttpClient(),
+			},
+			want: &Red{
+				StatusCode: 415,
+			},
+		},
+		{
+			name: "Wrong method",
+			r: &Red{
+				Method:  "PATCH",
+				Target:  "/hello",
+				Header:  http.Header{"Content-Type": {"application/json"}},
+				Payload: `{"message":"World"}`,
+			},
+			args: args{
+				client: pool.GetHttpClient(),
+			},
+			want: &Red{
+				StatusCode: 405,
+			},
+		},
+		{
+			name: "Invalid url",
+			r: &Red{
+				Method: "GET",
+				Target: "/coupons/50%off",
+			},
+			args: args{
+				client: pool.GetHttpClient(),
+			},
+			want: &Red{
+				StatusCode: -1,
+				NetError:   dto.NetErrorInvalidRequest,
+			},
+		},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /hello", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "Unsupported media type", http.StatusUnsupportedMediaType)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			slog.Error("Can't read body", "error", err)
+			http.Error(w, "Can't read body", http.StatusInternalServerError)
+			return
+		}
+		var message struct {
+			Message string `json:"message"`
+		}
+		err = json.Unmarshal(body, &message)
+		if err != nil {
+			slog.Error("Can't unmarshal json", "error", err)
+			http.Error(w, "Can't unmarshal json", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, "Hello, %s!", message.Message)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	for _, tt := range tests {
+		tt.r.Target = server.URL + tt.r.Target
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.Do(context.Background(), tt.args.client); got.StatusCode != tt.want.StatusCode || got.NetError != tt.want.NetError {
+				t.Errorf("Red.Do() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
 
-// 		t.Run(tt.name, func(t *testing.T) {
-// 			if got := tt.r.Post(tt.args.client); got.StatusCode != tt.want.StatusCode {
-// 				t.Errorf("Red.Post() = %v, want %v", got, tt.want)
-// 			}
-// 		})
-// 	}
-// }
+func TestRed_Do_Phases(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("Hello, World!"))
+	})
+	tests := []struct {
+		name   string
+		server *httptest.Server
+		tls    bool
+	}{
+		{name: "HTTP", server: httptest.NewServer(handler)},
+		{name: "HTTPS", server: httptest.NewTLSServer(handler), tls: true},
+	}
+	for _, tt := range tests {
+		defer tt.server.Close()
+		t.Run(tt.name, func(t *testing.T) {
+			client := tt.server.Client()
+			first := (&Red{Target: tt.server.URL}).Get(context.Background(), client)
+			if first.Phases.Reused || first.Phases.Connect <= 0 || (first.Phases.TLS > 0) != tt.tls || first.Phases.TTFB < 10*time.Millisecond {
+				t.Errorf("Red.Do() first Phases = %+v", first.Phases)
+			}
+			second := (&Red{Target: tt.server.URL}).Get(context.Background(), client)
+			if !second.Phases.Reused || second.Phases.Connect != 0 || second.Phases.TLS != 0 || second.Phases.TTFB < 10*time.Millisecond {
+				t.Errorf("Red.Do() second Phases = %+v", second.Phases)
+			}
+		})
+	}
+}
+
+func TestRed_Do_Bytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("Hello, World!"))
+	}))
+	defer server.Close()
+	tests := []struct {
+		name    string
+		r       *Red
+		payload int64
+	}{
+		{name: "Without body", r: &Red{Target: server.URL}},
+		{name: "With body", r: &Red{Method: "POST", Target: server.URL, Payload: `{"message":"World"}`}, payload: 19},
+		{name: "Captured", r: &Red{Target: server.URL, Capture: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.r.Do(context.Background(), server.Client())
+			// The request line and the Host and User-Agent headers alone are over 40 bytes.
+			if got.BytesSent < 40+tt.payload {
+				t.Errorf("Red.Do() BytesSent = %v, want at least %v", got.BytesSent, 40+tt.payload)
+			}
+			// The status line, Content-Type, Content-Length and Date headers and the body.
+			if got.BytesReceived < 100 || got.BytesReceived > 200 {
+				t.Errorf("Red.Do() BytesReceived = %v, want between 100 and 200", got.BytesReceived)
+			}
+		})
+	}
+}
+
+func TestRed_Do_BodyError(t *testing.T) {
+	// slow sends the first bytes of the body and then stalls.
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("Hello"))
+		http.NewResponseController(w).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		timeout time.Duration
+		cancel  time.Duration
+		want    dto.NetError
+	}{
+		{
+			name: "Hang up mid-body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				conn, buf, err := http.NewResponseController(w).Hijack()
+				if err != nil {
+					t.Errorf("Hijack() error = %v", err)
+					return
+				}
+				defer conn.Close()
+				buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\nHello")
+				buf.Flush()
+			},
+			want: dto.NetErrorEOF,
+		},
+		{
+			name:    "Timeout mid-body",
+			handler: slow,
+			timeout: 50 * time.Millisecond,
+			want:    dto.NetErrorTimeout,
+		},
+		{
+			name:    "Canceled mid-body",
+			handler: slow,
+			cancel:  50 * time.Millisecond,
+			want:    dto.NetErrorCanceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			client := server.Client()
+			client.Timeout = tt.timeout
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel > 0 {
+				time.AfterFunc(tt.cancel, cancel)
+			}
+			got := (&Red{Target: server.URL}).Get(ctx, client)
+			if got.StatusCode != -1 || got.NetError != tt.want || got.ErrorMessage == "" {
+				t.Errorf("Red.Do() = %d %s %q, want -1 %s", got.StatusCode, got.NetError, got.ErrorMessage, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package entity
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+type LoadProfile struct {
+	Stages []dto.Stage
+}
+
+// ParseStages parses a comma separated list of duration:target pairs, like
+// "2m:100,10m:100,1m:0", into stages. Durations use the time.ParseDuration syntax and
+// targets are the number of workers to reach at the end of the stage.
+func ParseStages(s string) ([]dto.Stage, error) {
+	var stages []dto.Stage
+	for _, part := range strings.Split(s, ",") {
+		d, t, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("stage %q must be in the form duration:target", part)
+		}
+		duration, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, fmt.Errorf("stage %q: %w", part, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("stage %q: duration must be greater than 0", part)
+		}
+		target, err := strconv.Atoi(t)
+		if err != nil {
+			return nil, fmt.Errorf("stage %q: %w", part, err)
+		}
+		if target < 0 {
+			return nil, fmt.Errorf("stage %q: target must not be negative", part)
+		}
+		stages = append(stages, dto.Stage{Duration: duration, Target: target})
+	}
+	return stages, nil
+}
+
+// Duration returns the total duration of the profile.
+func (p *LoadProfile) Duration() time.Duration {
+	var total time.Duration
+	for _, s := range p.Stages {
+		total += s.Duration
+	}
+	return total
+}
+
+// MaxConcurrency returns the highest number of workers the profile ever asks for.
+func (p *LoadProfile) MaxConcurrency() int {
+	m := 0
+	for _, s := range p.Stages {
+		m = max(m, s.Target)
+	}
+	return m
+}
+
+// At returns the index of the stage running at the given elapsed time since the start of
+// the profile and the number of workers that should be active at that time. ok is false
+// once the profile is over.
+func (p *LoadProfile) At(elapsed time.Duration) (stage int, concurrency int, ok bool) {
+	from := 0
+	for i, s := range p.Stages {
+		if elapsed < s.Duration {
+			progress := float64(elapsed) / float64(s.Duration)
+			return i, int(math.Round(float64(from) + float64(s.Target-from)*progress)), true
+		}
+		elapsed -= s.Duration
+		from = s.Target
+	}
+	return len(p.Stages) - 1, from, false
+}
@@ -0,0 +1,90 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+func TestParseStages(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []dto.Stage
+		wantErr bool
+	}{
+		{
+			name: "Success",
+			s:    "2m:100, 10m:100,1m:0",
+			want: []dto.Stage{
+				{Duration: 2 * time.Minute, Target: 100},
+				{Duration: 10 * time.Minute, Target: 100},
+				{Duration: time.Minute, Target: 0},
+			},
+		},
+		{
+			name:    "Missing target",
+			s:       "2m",
+			wantErr: true,
+		},
+		{
+			name:    "Bad duration",
+			s:       "2x:10",
+			wantErr: true,
+		},
+		{
+			name:    "Negative target",
+			s:       "2m:-1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStages(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseStages() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseStages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProfile_At(t *testing.T) {
+	p := &LoadProfile{Stages: []dto.Stage{
+		{Duration: 2 * time.Minute, Target: 100},
+		{Duration: 10 * time.Minute, Target: 100},
+		{Duration: time.Minute, Target: 0},
+	}}
+	tests := []struct {
+		name            string
+		elapsed         time.Duration
+		wantStage       int
+		wantConcurrency int
+		wantOk          bool
+	}{
+		{name: "Start", elapsed: 0, wantStage: 0, wantConcurrency: 0, wantOk: true},
+		{name: "Ramp up", elapsed: time.Minute, wantStage: 0, wantConcurrency: 50, wantOk: true},
+		{name: "Plateau", elapsed: 5 * time.Minute, wantStage: 1, wantConcurrency: 100, wantOk: true},
+		{name: "Ramp down", elapsed: 12*time.Minute + 15*time.Second, wantStage: 2, wantConcurrency: 75, wantOk: true},
+		{name: "Over", elapsed: 13 * time.Minute, wantStage: 2, wantConcurrency: 0, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage, concurrency, ok := p.At(tt.elapsed)
+			if stage != tt.wantStage || concurrency != tt.wantConcurrency || ok != tt.wantOk {
+				t.Errorf("LoadProfile.At() = %v, %v, %v, want %v, %v, %v", stage, concurrency, ok, tt.wantStage, tt.wantConcurrency, tt.wantOk)
+			}
+		})
+	}
+	if got := p.Duration(); got != 13*time.Minute {
+		t.Errorf("LoadProfile.Duration() = %v, want %v", got, 13*time.Minute)
+	}
+	if got := p.MaxConcurrency(); got != 100 {
+		t.Errorf("LoadProfile.MaxConcurrency() = %v, want %v", got, 100)
+	}
+}
@@ -0,0 +1,127 @@
+package entity
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"stress-tester/internal/dto"
+)
+
+// placeholder matches the {{name}} placeholders of a template, spaces allowed around the
+// name.
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// Render replaces every {{name}} placeholder in s by the value of name in vars.
+// Placeholders without a value are left as they are.
+func Render(s string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(s, "{{") {
+		return s
+	}
+	return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := vars[placeholder.FindStringSubmatch(m)[1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// RenderURL replaces every {{name}} placeholder in the url s like Render, escaping each
+// value for the part of the url it lands in: query-escaped in the query and fragment,
+// path-escaped in the path. Values before the path, like a base url, are left as they are.
+func RenderURL(s string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(s, "{{") {
+		return s
+	}
+	path := strings.Index(s, "://") + 3
+	if path < 3 {
+		path = 0
+	}
+	if i := strings.IndexByte(s[path:], '/'); i >= 0 {
+		path += i
+	} else {
+		path = len(s)
+	}
+	query := strings.IndexAny(s[path:], "?#")
+	if query >= 0 {
+		query += path
+	} else {
+		query = len(s)
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range placeholder.FindAllStringSubmatchIndex(s, -1) {
+		v, ok := vars[s[m[2]:m[3]]]
+		if !ok {
+			continue
+		}
+		switch {
+		case m[0] >= query:
+			v = url.QueryEscape(v)
+		case m[0] >= path:
+			v = url.PathEscape(v)
+		}
+		b.WriteString(s[last:m[0]])
+		b.WriteString(v)
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// IsTemplate tells whether s has any {{name}} placeholder.
+func IsTemplate(s string) bool {
+	return placeholder.MatchString(s)
+}
+
+// Placeholders returns the names of the {{name}} placeholders in the url, header values
+// and body of the endpoint.
+func Placeholders(e dto.Endpoint) []string {
+	var names []string
+	texts := []string{e.URL, e.Body}
+	for _, values := range e.Header {
+		texts = append(texts, values...)
+	}
+	for _, s := range texts {
+		for _, m := range placeholder.FindAllStringSubmatch(s, -1) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// RenderEndpoint returns a copy of the endpoint with the placeholders in its url, header
+// values and body replaced by the values in vars, escaped in the url, see RenderURL.
+func RenderEndpoint(e dto.Endpoint, vars map[string]string) dto.Endpoint {
+	if len(vars) == 0 {
+		return e
+	}
+	header := make(http.Header, len(e.Header))
+	for k, values := range e.Header {
+		for _, v := range values {
+			header.Add(k, Render(v, vars))
+		}
+	}
+	e.URL = RenderURL(e.URL, vars)
+	e.Header = header
+	e.Body = Render(e.Body, vars)
+	return e
+}
+
+// CheckRequest returns why the request of the rendered endpoint could not be sent, its url
+// not parsing or one of its header values having a control character, nil when it can.
+func CheckRequest(e dto.Endpoint) error {
+	if _, err := http.NewRequest(e.Method, e.URL, nil); err != nil {
+		return err
+	}
+	for k, values := range e.Header {
+		for _, v := range values {
+			if strings.ContainsFunc(v, func(r rune) bool { return r < ' ' && r != '\t' || r == 0x7f }) {
+				return fmt.Errorf("header %s: invalid value %q", k, v)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package entity
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+// thresholdSyntax matches a threshold like "p99<300ms" or "status_5xx <= 1%".
+var thresholdSyntax = regexp.MustCompile(`^\s*([a-z0-9_.]+)\s*(<=|>=|<|>)\s*(\S+)\s*$`)
+
+// statusMetric matches the metrics of a status code, as in status_500, or of a class of
+// status codes, as in status_5xx.
+var statusMetric = regexp.MustCompile(`^status_([1-5](?:xx|[0-9]{2}))$`)
+
+// ParseThreshold parses a threshold, see dto.Threshold. Response times take a duration,
+// like 300ms, the error rate a percentage, like 1%, and the status metrics either a
+// number or a percentage.
+func ParseThreshold(s string) (dto.Threshold, error) {
+	m := thresholdSyntax.FindStringSubmatch(s)
+	if m == nil {
+		return dto.Threshold{}, fmt.Errorf("threshold %q must be in the form metric<value, as in p99<300ms", s)
+	}
+	t := dto.Threshold{Text: strings.Join(m[1:], ""), Metric: m[1], Op: m[2]}
+	value := m[3]
+	var err error
+	switch {
+	case IsLatencyMetric(t.Metric):
+		var d time.Duration
+		d, err = time.ParseDuration(value)
+		t.Value = float64(d)
+		t.Percentile, _ = percentileOf(t.Metric)
+	case t.Metric == "error_rate":
+		if !strings.HasSuffix(value, "%") {
+			err = fmt.Errorf("error_rate must be a percentage, as in 1%%")
+			break
+		}
+		t.Value, err = parsePercent(value)
+		t.Rate = true
+	case t.Metric == "rps" || t.Metric == "requests" || t.Metric == "errors":
+		t.Value, err = strconv.ParseFloat(value, 64)
+	case statusMetric.MatchString(t.Metric):
+		if strings.HasSuffix(value, "%") {
+			t.Value, err = parsePercent(value)
+			t.Rate = true
+		} else {
+			t.Value, err = strconv.ParseFloat(value, 64)
+		}
+	default:
+		err = fmt.Errorf("metric %q must be one of pNN, avg, min, max, error_rate, rps, requests, errors or status_NNN", t.Metric)
+	}
+	if err != nil {
+		return dto.Threshold{}, fmt.Errorf("threshold %q: %w", s, err)
+	}
+	return t, nil
+}
+
+// IsLatencyMetric tells whether the metric is a response time: avg, min, max or a
+// percentile between p0 and p100, as in p99 or p99.9.
+func IsLatencyMetric(metric string) bool {
+	switch metric {
+	case "avg", "min", "max":
+		return true
+	}
+	p, ok := percentileOf(metric)
+	return ok && p > 0 && p <= 100
+}
+
+// percentileOf returns the percentile of a pNN metric, as 99.9 for p99.9.
+func percentileOf(metric string) (float64, bool) {
+	if !strings.HasPrefix(metric, "p") {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(metric[1:], 64)
+	return p, err == nil
+}
+
+// parsePercent parses a percentage, as in 1.5%, into a share between 0 and 1.
+func parsePercent(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 || v > 100 {
+		return 0, fmt.Errorf("percentage %s must be between 0%% and 100%%", s)
+	}
+	return v / 100, nil
+}
+
+// abortSyntax splits an abort condition like "error_rate>50% for 10s" into its threshold
+// and its window.
+var abortSyntax = regexp.MustCompile(`^(.*?)(?:\s+for\s+(\S+))?\s*$`)
+
+// DefaultAbortWindow is the window of an abort condition that does not set one.
+const DefaultAbortWindow = 10 * time.Second
+
+// ParseAbort parses an abort condition, a threshold optionally followed by "for" and the
+// window it is checked over, as in "error_rate>50% for 10s" or "p95>5s", see dto.Abort.
+// The window defaults to DefaultAbortWindow.
+func ParseAbort(s string) (dto.Abort, error) {
+	m := abortSyntax.FindStringSubmatch(s)
+	condition, err := ParseThreshold(m[1])
+	if err != nil {
+		return dto.Abort{}, fmt.Errorf("abort %q: %w", s, err)
+	}
+	a := dto.Abort{Text: condition.Text, Condition: condition, Window: DefaultAbortWindow}
+	if m[2] != "" {
+		a.Window, err = time.ParseDuration(m[2])
+		if err != nil || a.Window <= 0 {
+			return dto.Abort{}, fmt.Errorf("abort %q: window %q must be a duration greater than 0", s, m[2])
+		}
+		a.Text += " for " + a.Window.String()
+	}
+	return a, nil
+}
@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    dto.Threshold
+		wantErr bool
+	}{
+		{s: "p99<300ms", want: dto.Threshold{Text: "p99<300ms", Metric: "p99", Op: "<", Value: float64(300 * time.Millisecond), Percentile: 99}},
+		{s: " p99.9 <= 1s ", want: dto.Threshold{Text: "p99.9<=1s", Metric: "p99.9", Op: "<=", Value: float64(time.Second), Percentile: 99.9}},
+		{s: "avg<100ms", want: dto.Threshold{Text: "avg<100ms", Metric: "avg", Op: "<", Value: float64(100 * time.Millisecond)}},
+		{s: "error_rate<1%", want: dto.Threshold{Text: "error_rate<1%", Metric: "error_rate", Op: "<", Value: 0.01, Rate: true}},
+		{s: "rps>200", want: dto.Threshold{Text: "rps>200", Metric: "rps", Op: ">", Value: 200}},
+		{s: "status_500<10", want: dto.Threshold{Text: "status_500<10", Metric: "status_500", Op: "<", Value: 10}},
+		{s: "status_5xx<=2.5%", want: dto.Threshold{Text: "status_5xx<=2.5%", Metric: "status_5xx", Op: "<=", Value: 0.025, Rate: true}},
+		{s: "p99", wantErr: true},
+		{s: "p99<300", wantErr: true},
+		{s: "p101<1s", wantErr: true},
+		{s: "error_rate<0.01", wantErr: true},
+		{s: "error_rate<101%", wantErr: true},
+		{s: "status_600<1", wantErr: true},
+		{s: "latency<1s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseThreshold(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseThreshold() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseThreshold() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAbort(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    dto.Abort
+		wantErr bool
+	}{
+		{s: "error_rate>50% for 10s", want: dto.Abort{Text: "error_rate>50% for 10s", Condition: dto.Threshold{Text: "error_rate>50%", Metric: "error_rate", Op: ">", Value: 0.5, Rate: true}, Window: 10 * time.Second}},
+		{s: "p95>5s", want: dto.Abort{Text: "p95>5s", Condition: dto.Threshold{Text: "p95>5s", Metric: "p95", Op: ">", Value: float64(5 * time.Second), Percentile: 95}, Window: DefaultAbortWindow}},
+		{s: "errors > 100 for 1m", want: dto.Abort{Text: "errors>100 for 1m0s", Condition: dto.Threshold{Text: "errors>100", Metric: "errors", Op: ">", Value: 100}, Window: time.Minute}},
+		{s: "p95>5s for ever", wantErr: true},
+		{s: "p95>5s for 0s", wantErr: true},
+		{s: "for 10s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseAbort(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAbort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAbort() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package entity
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+// trace records the phases of a request from the hooks of net/http/httptrace. The hooks
+// may be called from other goroutines, even after the response arrived, as when a
+// connection dialed for the request ends up unused, so they hold mu.
+type trace struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wrote        time.Time
+	firstByte    time.Time
+	phases       dto.Phases
+}
+
+// clientTrace returns the hooks recording the phases of the request in the trace.
+func (t *trace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.phases.DNS = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil && t.phases.Connect == 0 {
+				t.phases.Connect = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.phases.TLS = time.Since(t.tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.phases.Reused = info.Reused
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.wrote = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.firstByte = time.Now()
+		},
+	}
+}
+
+// result returns the phases of a request sent at sentAt whose response was read by
+// receivedAt. The time to first byte counts from sentAt when the request was never
+// written, and the phases past it are zero when no response arrived.
+func (t *trace) result(sentAt, receivedAt time.Time) dto.Phases {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	phases := t.phases
+	if t.firstByte.IsZero() {
+		return phases
+	}
+	wrote := t.wrote
+	if wrote.IsZero() || wrote.After(t.firstByte) {
+		wrote = sentAt
+	}
+	phases.TTFB = t.firstByte.Sub(wrote)
+	phases.Transfer = max(receivedAt.Sub(t.firstByte), 0)
+	return phases
+}
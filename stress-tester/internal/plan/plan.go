@@ -0,0 +1,678 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"stress-tester/internal/dto"
+	"stress-tester/internal/entity"
+	"stress-tester/internal/stats"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Plan is a full test plan, as declared in a YAML or JSON file: the targets to stress, or
+// the journeys the virtual users run, the load model and how the run is checked, stopped
+// and reported.
+type Plan struct {
+	Targets     []Target  `yaml:"targets" json:"targets"`
+	Journeys    []Journey `yaml:"journeys" json:"journeys"`
+	Feeders     []Feeder  `yaml:"feeders" json:"feeders"`
+	Load        Load      `yaml:"load" json:"load"`
+	Timeout     Duration  `yaml:"timeout" json:"timeout"`
+	Thresholds  []string  `yaml:"thresholds" json:"thresholds"`
+	Abort       []string  `yaml:"abort" json:"abort"`
+	Grace       Duration  `yaml:"grace" json:"grace"`
+	Interval    Duration  `yaml:"interval" json:"interval"`
+	Precision   int       `yaml:"precision" json:"precision"`
+	Percentiles []float64 `yaml:"percentiles" json:"percentiles"`
+	Outputs     []Output  `yaml:"outputs" json:"outputs"`
+	Store       Store     `yaml:"store" json:"store"`
+	GitSHA      string    `yaml:"git_sha" json:"git_sha"`
+	Tags        []string  `yaml:"tags" json:"tags"`
+
+	// dir is the directory of the plan file, body and feeder files are relative to it.
+	dir string
+}
+
+type Target struct {
+	Name     string                  `yaml:"name" json:"name"`
+	Weight   int                     `yaml:"weight" json:"weight"`
+	URL      string                  `yaml:"url" json:"url"`
+	Method   string                  `yaml:"method" json:"method"`
+	Headers  map[string]HeaderValues `yaml:"headers" json:"headers"`
+	Body     string                  `yaml:"body" json:"body"`
+	BodyFile string                  `yaml:"body_file" json:"body_file"`
+	Checks   []Check                 `yaml:"checks" json:"checks"`
+	Extract  []Extractor             `yaml:"extract" json:"extract"`
+}
+
+// HeaderValues are the values of a header of a target, read from a single string, or from
+// a list of strings when the header is sent more than once.
+type HeaderValues []string
+
+func (v *HeaderValues) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*v = HeaderValues{node.Value}
+		return nil
+	}
+	return node.Decode((*[]string)(v))
+}
+
+func (v *HeaderValues) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = HeaderValues{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(v))
+}
+
+// Journey is an ordered flow of steps run by a virtual user. Values extracted from the
+// response of a step are used by the next ones as {{name}} in their url, headers and body.
+type Journey struct {
+	Name   string   `yaml:"name" json:"name"`
+	Weight int      `yaml:"weight" json:"weight"`
+	Steps  []Target `yaml:"steps" json:"steps"`
+}
+
+// Extractor takes the value of the variable Name out of a response, with exactly one of
+// a JSONPath, a regular expression or a header name.
+type Extractor struct {
+	Name   string `yaml:"name" json:"name"`
+	JSON   string `yaml:"json" json:"json"`
+	Regex  string `yaml:"regex" json:"regex"`
+	Header string `yaml:"header" json:"header"`
+}
+
+// toDTO returns the extractor as a dto.Extractor, with an empty From when it does not
+// set exactly one of JSON, Regex and Header.
+func (x Extractor) toDTO() dto.Extractor {
+	e := dto.Extractor{Name: x.Name}
+	set := 0
+	for from, expr := range map[string]string{"json": x.JSON, "regex": x.Regex, "header": x.Header} {
+		if expr != "" {
+			e.From, e.Expr = from, expr
+			set++
+		}
+	}
+	if set != 1 {
+		e.From = ""
+	}
+	return e
+}
+
+// Feeder is a CSV or JSONL file whose rows are used by the targets and steps as
+// {{name.column}}. Mode is sequential, the default, random or unique.
+type Feeder struct {
+	Name string `yaml:"name" json:"name"`
+	File string `yaml:"file" json:"file"`
+	Mode string `yaml:"mode" json:"mode"`
+
+	// rows are the rows read from File by Validate.
+	rows []map[string]string
+}
+
+// Check is an expectation the responses of a target, or step, must meet to be a success,
+// with exactly one of: a list of accepted status codes, a text the body contains, a
+// regular expression the body matches, a JSONPath found in the body, optionally with the
+// value it must be equal to, a header that must be present or the max latency.
+type Check struct {
+	Name         string   `yaml:"name" json:"name"`
+	Status       []int    `yaml:"status" json:"status"`
+	BodyContains string   `yaml:"body_contains" json:"body_contains"`
+	BodyRegex    string   `yaml:"body_regex" json:"body_regex"`
+	JSONPath     string   `yaml:"json_path" json:"json_path"`
+	Equals       string   `yaml:"equals" json:"equals"`
+	Header       string   `yaml:"header" json:"header"`
+	MaxLatency   Duration `yaml:"max_latency" json:"max_latency"`
+}
+
+// toDTO returns the check as a dto.Check, with an empty Type when it does not set exactly
+// one expectation.
+func (c Check) toDTO() dto.Check {
+	d := dto.Check{Name: c.Name, Status: c.Status, Equals: c.Equals, MaxLatency: time.Duration(c.MaxLatency)}
+	set := 0
+	for _, v := range []struct {
+		kind, expr string
+		ok         bool
+	}{
+		{"status", "", len(c.Status) > 0},
+		{"body_contains", c.BodyContains, c.BodyContains != ""},
+		{"body_regex", c.BodyRegex, c.BodyRegex != ""},
+		{"json", c.JSONPath, c.JSONPath != ""},
+		{"header", c.Header, c.Header != ""},
+		{"max_latency", "", c.MaxLatency != 0},
+	} {
+		if v.ok {
+			d.Type, d.Expr = v.kind, v.expr
+			set++
+		}
+	}
+	if set != 1 {
+		d.Type = ""
+	}
+	return d
+}
+
+type Load struct {
+	Requests    int      `yaml:"requests" json:"requests"`
+	Concurrency int      `yaml:"concurrency" json:"concurrency"`
+	Duration    Duration `yaml:"duration" json:"duration"`
+	Rate        float64  `yaml:"rate" json:"rate"`
+	MaxInFlight int      `yaml:"max_in_flight" json:"max_in_flight"`
+	Stages      []Stage  `yaml:"stages" json:"stages"`
+}
+
+type Stage struct {
+	Duration Duration `yaml:"duration" json:"duration"`
+	Target   int      `yaml:"target" json:"target"`
+}
+
+// Output is a format the report is written in. An empty Path writes to the console.
+type Output struct {
+	Format string `yaml:"format" json:"format"`
+	Path   string `yaml:"path" json:"path"`
+}
+
+// Store is where the records of the run are kept, see dto.Store. Path is required by the
+// file store, and keeps the records of the sqlite store in a file, when set, instead of in
+// memory.
+type Store struct {
+	Kind string `yaml:"kind" json:"kind"`
+	Path string `yaml:"path" json:"path"`
+}
+
+// StoreKinds lists the stores a plan can ask for.
+var StoreKinds = []string{"sqlite", "memory", "file"}
+
+// Formats lists the output formats a plan can ask for.
+var Formats = []string{"text", "json"}
+
+// FeederModes lists the modes a feeder can use.
+var FeederModes = []string{"sequential", "random", "unique"}
+
+// Duration is a time.Duration read from a string like "15m" or "300ms".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// New returns an empty plan with the default concurrency of 10, grace of 10s, interval of
+// 1s, precision of 3 significant digits and percentiles.
+func New() *Plan {
+	return &Plan{
+		Load:        Load{Concurrency: 10},
+		Grace:       Duration(10 * time.Second),
+		Interval:    Duration(time.Second),
+		Precision:   stats.DefaultDigits,
+		Percentiles: stats.DefaultPercentiles,
+	}
+}
+
+// Read parses the plan file at the given path. Files ending in .json are read as JSON,
+// anything else as YAML. Unknown fields are reported as errors so that typos in the plan
+// do not go unnoticed.
+func Read(path string) (*Plan, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := New()
+	p.dir = filepath.Dir(path)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(p)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		err = dec.Decode(p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate checks the plan and returns the list of problems found, empty when the plan
+// can be run. It fills in the defaults and reads the body and feeder files.
+func (p *Plan) Validate() []string {
+	errors := p.validateFeeders()
+
+	if len(p.Targets) == 0 && len(p.Journeys) == 0 {
+		errors = append(errors, "at least one target or journey is required")
+	}
+	if len(p.Targets) > 0 && len(p.Journeys) > 0 {
+		errors = append(errors, "targets and journeys can not be used together")
+	}
+	names := map[string]bool{}
+	for i := range p.Targets {
+		t := &p.Targets[i]
+		label := t.Name
+		if label == "" {
+			label = fmt.Sprintf("targets[%d]", i)
+		}
+		errors = append(errors, p.validateTarget(t, label, names)...)
+		if len(t.Extract) > 0 {
+			errors = append(errors, fmt.Sprintf("%s: extract can only be used in the steps of a journey", label))
+		}
+		for _, name := range entity.Placeholders(t.toDTO("")) {
+			if !strings.Contains(name, ".") {
+				errors = append(errors, fmt.Sprintf("%s: {{%s}} is not a column of a feeder", label, name))
+			}
+		}
+	}
+	journeys := map[string]bool{}
+	for i := range p.Journeys {
+		j := &p.Journeys[i]
+		label := j.Name
+		if label == "" {
+			label = fmt.Sprintf("journeys[%d]", i)
+			errors = append(errors, fmt.Sprintf("%s: name must not be empty", label))
+		}
+		if journeys[j.Name] {
+			errors = append(errors, fmt.Sprintf("%s: name must be unique", label))
+		}
+		journeys[j.Name] = true
+		if j.Weight < 0 {
+			errors = append(errors, fmt.Sprintf("%s: weight must not be negative", label))
+		}
+		if j.Weight == 0 {
+			j.Weight = 1
+		}
+		if len(j.Steps) == 0 {
+			errors = append(errors, fmt.Sprintf("%s: at least one step is required", label))
+		}
+		steps := map[string]bool{}
+		for k := range j.Steps {
+			t := &j.Steps[k]
+			stepLabel := t.Name
+			if stepLabel == "" {
+				stepLabel = fmt.Sprintf("steps[%d]", k)
+			}
+			stepLabel = label + ", " + stepLabel
+			errors = append(errors, p.validateTarget(t, stepLabel, steps)...)
+			for _, x := range t.Extract {
+				e := x.toDTO()
+				if e.From == "" {
+					errors = append(errors, fmt.Sprintf("%s: extractor %s must set exactly one of json, regex or header", stepLabel, x.Name))
+					continue
+				}
+				if _, err := entity.NewExtractor(e); err != nil {
+					errors = append(errors, fmt.Sprintf("%s: %s", stepLabel, err.Error()))
+				}
+			}
+		}
+	}
+
+	l := &p.Load
+	if l.Requests <= 0 && l.Duration == 0 && len(l.Stages) == 0 {
+		errors = append(errors, "requests must be greater than 0")
+	}
+	if l.Duration < 0 {
+		errors = append(errors, "duration must not be negative")
+	}
+	if l.Rate < 0 {
+		errors = append(errors, "rate must not be negative")
+	}
+	if l.MaxInFlight < 0 {
+		errors = append(errors, "max_in_flight must not be negative")
+	}
+	if l.MaxInFlight == 0 {
+		l.MaxInFlight = l.Concurrency
+	}
+	if len(l.Stages) > 0 && l.Rate > 0 {
+		errors = append(errors, "stages can not be used with rate")
+	}
+	busy := false
+	for i, s := range l.Stages {
+		if s.Duration <= 0 {
+			errors = append(errors, fmt.Sprintf("stages[%d]: duration must be greater than 0", i))
+		}
+		if s.Target < 0 {
+			errors = append(errors, fmt.Sprintf("stages[%d]: target must not be negative", i))
+		}
+		busy = busy || s.Target > 0
+	}
+	// Without a worker in any stage the run would send nothing until the profile ends.
+	if len(l.Stages) > 0 && !busy {
+		errors = append(errors, "stages: at least one stage must have a target greater than 0")
+	}
+	if l.Concurrency <= 0 {
+		errors = append(errors, "concurrency must be greater than 0")
+	}
+	if p.Timeout < 0 {
+		errors = append(errors, "timeout must not be negative")
+	}
+	if p.Grace < 0 {
+		errors = append(errors, "grace must not be negative")
+	}
+	if p.Interval <= 0 {
+		errors = append(errors, "interval must be greater than 0")
+	}
+	if p.Precision < 1 || p.Precision > 5 {
+		errors = append(errors, "precision must be between 1 and 5 significant digits")
+	}
+	if len(p.Percentiles) == 0 {
+		errors = append(errors, "percentiles must not be empty")
+	}
+	for i, pc := range p.Percentiles {
+		if err := entity.CheckPercentile(pc); err != nil {
+			errors = append(errors, fmt.Sprintf("percentiles[%d]: %s", i, err))
+		}
+	}
+	for _, t := range p.Thresholds {
+		if _, err := entity.ParseThreshold(t); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	for _, a := range p.Abort {
+		if _, err := entity.ParseAbort(a); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
+	if len(p.Outputs) == 0 {
+		p.Outputs = []Output{{Format: "text"}}
+	}
+	for i, o := range p.Outputs {
+		if !slices.Contains(Formats, o.Format) {
+			errors = append(errors, fmt.Sprintf("outputs[%d]: format %q must be one of %s", i, o.Format, strings.Join(Formats, ", ")))
+		}
+	}
+	if p.Store.Kind == "" {
+		p.Store.Kind = "sqlite"
+	}
+	if !slices.Contains(StoreKinds, p.Store.Kind) {
+		errors = append(errors, fmt.Sprintf("store: kind %q must be one of %s", p.Store.Kind, strings.Join(StoreKinds, ", ")))
+	}
+	if p.Store.Kind == "file" && p.Store.Path == "" {
+		errors = append(errors, "store: path must not be empty for the file store")
+	}
+	for i, t := range p.Tags {
+		if strings.TrimSpace(t) == "" {
+			errors = append(errors, fmt.Sprintf("tags[%d]: tag must not be empty", i))
+		}
+	}
+	return errors
+}
+
+// validateFeeders checks the feeders and reads their files, returning the problems found.
+func (p *Plan) validateFeeders() []string {
+	errors := []string{}
+	names := map[string]bool{}
+	for i := range p.Feeders {
+		f := &p.Feeders[i]
+		label := f.Name
+		if label == "" {
+			label = fmt.Sprintf("feeders[%d]", i)
+			errors = append(errors, fmt.Sprintf("%s: name must not be empty", label))
+		}
+		if strings.ContainsAny(f.Name, ".{} ") {
+			errors = append(errors, fmt.Sprintf("%s: name must not have dots, braces or spaces", label))
+		}
+		if names[f.Name] {
+			errors = append(errors, fmt.Sprintf("%s: name must be unique", label))
+		}
+		names[f.Name] = true
+		if f.Mode == "" {
+			f.Mode = "sequential"
+		}
+		if !slices.Contains(FeederModes, f.Mode) {
+			errors = append(errors, fmt.Sprintf("%s: mode %q must be one of %s", label, f.Mode, strings.Join(FeederModes, ", ")))
+		}
+		if f.File == "" {
+			errors = append(errors, fmt.Sprintf("%s: file must not be empty", label))
+			continue
+		}
+		path := f.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(p.dir, path)
+		}
+		rows, err := entity.ReadFeeder(path)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %s", label, err.Error()))
+		}
+		f.rows = rows
+	}
+	return errors
+}
+
+// checkColumns returns the problems of the {{feeder.column}} placeholders of a target, or
+// the step of a journey, naming a feeder of the plan but not one of its columns, or a row
+// of the feeder that can not be sent, see entity.CheckRequest. Only the first bad row of
+// each feeder is reported.
+func (p *Plan) checkColumns(t *Target, label string) []string {
+	errors := []string{}
+	e := t.toDTO("")
+	used := map[int][]string{}
+	for _, name := range entity.Placeholders(e) {
+		feeder, column, ok := strings.Cut(name, ".")
+		if !ok {
+			continue
+		}
+		i := slices.IndexFunc(p.Feeders, func(f Feeder) bool { return f.Name == feeder })
+		if i < 0 {
+			errors = append(errors, fmt.Sprintf("%s: {{%s}} uses feeder %s, which is not declared", label, name, feeder))
+			continue
+		}
+		if rows := p.Feeders[i].rows; len(rows) > 0 {
+			if _, ok := rows[0][column]; !ok {
+				errors = append(errors, fmt.Sprintf("%s: {{%s}} is not a column of feeder %s", label, name, feeder))
+				continue
+			}
+		}
+		used[i] = append(used[i], column)
+	}
+	// The columns of the other feeders are taken from their first rows.
+	vars := map[string]string{}
+	for _, f := range p.Feeders {
+		if len(f.rows) > 0 {
+			for k, v := range f.rows[0] {
+				vars[f.Name+"."+k] = v
+			}
+		}
+	}
+	for _, i := range slices.Sorted(maps.Keys(used)) {
+		f := p.Feeders[i]
+		for n, row := range f.rows {
+			if column := slices.IndexFunc(used[i], func(c string) bool { _, ok := row[c]; return !ok }); column >= 0 {
+				errors = append(errors, fmt.Sprintf("%s: row %d of feeder %s: {{%s.%s}} is not one of its columns", label, n+1, f.Name, f.Name, used[i][column]))
+				break
+			}
+			rowVars := maps.Clone(vars)
+			for k, v := range row {
+				rowVars[f.Name+"."+k] = v
+			}
+			if err := entity.CheckRequest(entity.RenderEndpoint(e, rowVars)); err != nil {
+				errors = append(errors, fmt.Sprintf("%s: row %d of feeder %s: %s", label, n+1, f.Name, err.Error()))
+				break
+			}
+		}
+	}
+	return errors
+}
+
+// validateTarget checks a target, or the step of a journey, filling in its defaults, and
+// returns the problems found, labeled with the given label. names holds the names of the
+// targets, or steps of the journey, already checked, as names must be unique.
+func (p *Plan) validateTarget(t *Target, label string, names map[string]bool) []string {
+	errors := []string{}
+	if t.URL == "" {
+		errors = append(errors, fmt.Sprintf("%s: url must not be empty", label))
+	} else if entity.IsTemplate(t.URL) {
+		if !strings.HasPrefix(t.URL, "http://") && !strings.HasPrefix(t.URL, "https://") {
+			errors = append(errors, fmt.Sprintf("%s: url %q must be an absolute http(s) url", label, t.URL))
+		}
+	} else if u, err := url.Parse(t.URL); err != nil || u.Scheme == "" || u.Host == "" {
+		errors = append(errors, fmt.Sprintf("%s: url %q must be an absolute http(s) url", label, t.URL))
+	}
+	if t.Method == "" {
+		t.Method = http.MethodGet
+	}
+	t.Method = strings.ToUpper(t.Method)
+	if t.Name == "" {
+		t.Name = t.Method + " " + t.URL
+	}
+	if names[t.Name] {
+		errors = append(errors, fmt.Sprintf("%s: name must be unique", label))
+	}
+	names[t.Name] = true
+	if t.Weight < 0 {
+		errors = append(errors, fmt.Sprintf("%s: weight must not be negative", label))
+	}
+	if t.Weight == 0 {
+		t.Weight = 1
+	}
+	if t.Body != "" && t.BodyFile != "" {
+		errors = append(errors, fmt.Sprintf("%s: body and body_file can not be used together", label))
+	}
+	if t.BodyFile != "" {
+		path := t.BodyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(p.dir, path)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %s", label, err.Error()))
+		}
+		t.Body = string(b)
+		t.BodyFile = ""
+	}
+	for i, c := range t.Checks {
+		d := c.toDTO()
+		switch {
+		case d.Type == "":
+			errors = append(errors, fmt.Sprintf("%s: checks[%d] must set exactly one of status, body_contains, body_regex, json_path, header or max_latency", label, i))
+		case c.Equals != "" && d.Type != "json":
+			errors = append(errors, fmt.Sprintf("%s: checks[%d]: equals can only be used with json_path", label, i))
+		default:
+			if _, err := entity.NewCheck(d); err != nil {
+				errors = append(errors, fmt.Sprintf("%s: %s", label, err.Error()))
+			}
+		}
+	}
+	errors = append(errors, p.checkColumns(t, label)...)
+	return errors
+}
+
+// Endpoints returns the targets of a validated plan as the endpoints the requests are
+// sent to.
+func (p *Plan) Endpoints() []dto.Endpoint {
+	endpoints := make([]dto.Endpoint, len(p.Targets))
+	for i, t := range p.Targets {
+		endpoints[i] = t.toDTO("")
+	}
+	return endpoints
+}
+
+// JourneyList returns the journeys of a validated plan, the name of each step prefixed by
+// the name of its journey, as in "checkout/login".
+func (p *Plan) JourneyList() []dto.Journey {
+	journeys := make([]dto.Journey, len(p.Journeys))
+	for i, j := range p.Journeys {
+		journeys[i] = dto.Journey{Name: j.Name, Weight: j.Weight}
+		for _, t := range j.Steps {
+			journeys[i].Steps = append(journeys[i].Steps, t.toDTO(j.Name+"/"))
+		}
+	}
+	return journeys
+}
+
+// toDTO returns the target as a dto.Endpoint, its name prefixed by prefix.
+func (t Target) toDTO(prefix string) dto.Endpoint {
+	header := http.Header{}
+	for k, values := range t.Headers {
+		for _, v := range values {
+			header.Add(k, v)
+		}
+	}
+	e := dto.Endpoint{
+		Name:   prefix + t.Name,
+		Weight: t.Weight,
+		Method: t.Method,
+		URL:    t.URL,
+		Header: header,
+		Body:   t.Body,
+	}
+	for _, c := range t.Checks {
+		e.Checks = append(e.Checks, c.toDTO())
+	}
+	for _, x := range t.Extract {
+		e.Extract = append(e.Extract, x.toDTO())
+	}
+	return e
+}
+
+// FeederList returns the feeders of a validated plan with their rows.
+func (p *Plan) FeederList() []dto.Feeder {
+	feeders := make([]dto.Feeder, len(p.Feeders))
+	for i, f := range p.Feeders {
+		feeders[i] = dto.Feeder{Name: f.Name, Mode: f.Mode, Rows: f.rows}
+	}
+	return feeders
+}
+
+// ToLoad returns the validated plan as the dto.Load run by the usecase.
+func (p *Plan) ToLoad() dto.Load {
+	stages := make([]dto.Stage, len(p.Load.Stages))
+	for i, s := range p.Load.Stages {
+		stages[i] = dto.Stage{Duration: time.Duration(s.Duration), Target: s.Target}
+	}
+	var thresholds []dto.Threshold
+	for _, s := range p.Thresholds {
+		t, _ := entity.ParseThreshold(s)
+		thresholds = append(thresholds, t)
+	}
+	var aborts []dto.Abort
+	for _, s := range p.Abort {
+		a, _ := entity.ParseAbort(s)
+		aborts = append(aborts, a)
+	}
+	outputs := make([]dto.Output, len(p.Outputs))
+	for i, o := range p.Outputs {
+		outputs[i] = dto.Output{Format: o.Format, Path: o.Path}
+	}
+	return dto.Load{
+		Endpoints:   p.Endpoints(),
+		Journeys:    p.JourneyList(),
+		Feeders:     p.FeederList(),
+		Requests:    p.Load.Requests,
+		Concurrency: p.Load.Concurrency,
+		Duration:    time.Duration(p.Load.Duration),
+		Rate:        p.Load.Rate,
+		MaxInFlight: p.Load.MaxInFlight,
+		Stages:      stages,
+		Timeout:     time.Duration(p.Timeout),
+		Thresholds:  thresholds,
+		Aborts:      aborts,
+		Grace:       time.Duration(p.Grace),
+		Interval:    time.Duration(p.Interval),
+		Precision:   p.Precision,
+		Percentiles: p.Percentiles,
+		Outputs:     outputs,
+		Store:       dto.Store{Kind: p.Store.Kind, Path: p.Store.Path},
+		GitSHA:      p.GitSHA,
+		Tags:        p.Tags,
+	}
+}
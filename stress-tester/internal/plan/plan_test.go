@@ -0,0 +1,241 @@
+package plan
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    dto.Load
+		wantErr bool
+	}{
+		{
+			name: "YAML",
+			path: "testdata/plan.yaml",
+			want: dto.Load{
+				Endpoints: []dto.Endpoint{
+					{Name: "products", Weight: 7, Method: "GET", URL: "http://localhost:8080/products", Header: http.Header{"Authorization": {"Bearer xyz"}, "Accept": {"application/json", "text/plain"}}},
+					{Name: "checkout", Weight: 1, Method: "POST", URL: "http://localhost:8080/checkout", Header: http.Header{}, Body: "{\"id\":1}\n", Checks: []dto.Check{
+						{Type: "status", Status: []int{200, 201}},
+						{Type: "json", Expr: "$.id", Equals: "1"},
+						{Name: "fast", Type: "max_latency", MaxLatency: 300 * time.Millisecond},
+					}},
+				},
+				Requests:    1000,
+				Concurrency: 50,
+				MaxInFlight: 50,
+				Stages: []dto.Stage{
+					{Duration: time.Minute, Target: 50},
+					{Duration: 30 * time.Second, Target: 0},
+				},
+				Journeys: []dto.Journey{},
+				Feeders:  []dto.Feeder{},
+				Timeout:  2 * time.Second,
+				Thresholds: []dto.Threshold{
+					{Text: "p99<300ms", Metric: "p99", Op: "<", Value: float64(300 * time.Millisecond), Percentile: 99},
+					{Text: "error_rate<1%", Metric: "error_rate", Op: "<", Value: 0.01, Rate: true},
+				},
+				Aborts: []dto.Abort{
+					{Text: "error_rate>50% for 30s", Condition: dto.Threshold{Text: "error_rate>50%", Metric: "error_rate", Op: ">", Value: 0.5, Rate: true}, Window: 30 * time.Second},
+				},
+				Grace:       10 * time.Second,
+				Interval:    time.Second,
+				Precision:   3,
+				Percentiles: []float64{50, 99, 99.9},
+				Outputs:     []dto.Output{{Format: "json", Path: "out.json"}},
+				Store:       dto.Store{Kind: "file", Path: "results.jsonl"},
+			},
+		},
+		{
+			name: "JSON",
+			path: "testdata/plan.json",
+			want: dto.Load{
+				Endpoints:   []dto.Endpoint{{Name: "GET http://localhost:8080/", Weight: 1, Method: "GET", URL: "http://localhost:8080/", Header: http.Header{"X-Env": {"staging"}, "X-Tag": {"a", "b"}}}},
+				Journeys:    []dto.Journey{},
+				Feeders:     []dto.Feeder{},
+				Concurrency: 10,
+				Duration:    15 * time.Minute,
+				Rate:        500,
+				MaxInFlight: 100,
+				Stages:      []dto.Stage{},
+				Grace:       10 * time.Second,
+				Interval:    time.Second,
+				Precision:   3,
+				Percentiles: []float64{50, 90, 95, 99, 99.9, 99.99},
+				Outputs:     []dto.Output{{Format: "text"}},
+				Store:       dto.Store{Kind: "sqlite", Path: "results.db"},
+				GitSHA:      "3f2c1ab",
+				Tags:        []string{"nightly", "env=staging"},
+			},
+		},
+		{
+			name: "Journeys",
+			path: "testdata/journey.yaml",
+			want: dto.Load{
+				Endpoints: []dto.Endpoint{},
+				Journeys: []dto.Journey{
+					{Name: "checkout", Weight: 3, Steps: []dto.Endpoint{
+						{Name: "checkout/login", Weight: 1, Method: "POST", URL: "http://localhost:8080/login", Header: http.Header{}, Body: `{"user":"a"}`, Extract: []dto.Extractor{
+							{Name: "token", From: "json", Expr: "$.token"},
+							{Name: "session", From: "header", Expr: "X-Session"},
+						}},
+						{Name: "checkout/cart", Weight: 1, Method: "GET", URL: "http://localhost:8080/cart/{{session}}", Header: http.Header{"Authorization": {"Bearer {{token}}"}}},
+					}},
+					{Name: "browse", Weight: 1, Steps: []dto.Endpoint{
+						{Name: "browse/GET http://localhost:8080/products", Weight: 1, Method: "GET", URL: "http://localhost:8080/products", Header: http.Header{}},
+					}},
+				},
+				Feeders:     []dto.Feeder{},
+				Concurrency: 10,
+				Duration:    time.Minute,
+				MaxInFlight: 10,
+				Stages:      []dto.Stage{},
+				Grace:       10 * time.Second,
+				Interval:    time.Second,
+				Precision:   3,
+				Percentiles: []float64{50, 90, 95, 99, 99.9, 99.99},
+				Outputs:     []dto.Output{{Format: "text"}},
+				Store:       dto.Store{Kind: "sqlite"},
+			},
+		},
+		{
+			name: "Feeders",
+			path: "testdata/feeder.yaml",
+			want: dto.Load{
+				Endpoints: []dto.Endpoint{
+					{Name: "user", Weight: 1, Method: "GET", URL: "http://localhost:8080/users/{{users.id}}", Header: http.Header{"X-Email": {"{{ users.email }}"}}},
+					{Name: "search", Weight: 1, Method: "POST", URL: "http://localhost:8080/search?q={{search.term}}", Header: http.Header{}, Body: "{{search.filters}}"},
+				},
+				Journeys: []dto.Journey{},
+				Feeders: []dto.Feeder{
+					{Name: "users", Mode: "unique", Rows: []map[string]string{
+						{"id": "1", "email": "ana@example.com"},
+						{"id": "2", "email": "bruno@example.com"},
+					}},
+					{Name: "search", Mode: "sequential", Rows: []map[string]string{
+						{"term": "shoes", "filters": `{"size":42}`},
+						{"term": "bags", "filters": ""},
+					}},
+				},
+				Requests:    2,
+				Concurrency: 10,
+				MaxInFlight: 10,
+				Stages:      []dto.Stage{},
+				Grace:       10 * time.Second,
+				Interval:    time.Second,
+				Precision:   3,
+				Percentiles: []float64{50, 90, 95, 99, 99.9, 99.99},
+				Outputs:     []dto.Output{{Format: "text"}},
+				Store:       dto.Store{Kind: "sqlite"},
+			},
+		},
+		{
+			name:    "Unknown field",
+			path:    "testdata/unknown.yaml",
+			wantErr: true,
+		},
+		{
+			name:    "Missing file",
+			path:    "testdata/missing.yaml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Read(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if errs := p.Validate(); len(errs) > 0 {
+				t.Fatalf("Validate() = %v, want no errors", errs)
+			}
+			if got := p.ToLoad(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToLoad() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlan_Validate(t *testing.T) {
+	p, err := Read("testdata/invalid.yaml")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	want := []string{
+		`relative: url "/products" must be an absolute http(s) url`,
+		"relative: body and body_file can not be used together",
+		"relative: name must be unique",
+		"targets[2]: weight must not be negative",
+		"targets[2]: checks[0] must set exactly one of status, body_contains, body_regex, json_path, header or max_latency",
+		"targets[2]: check body matches (: error parsing regexp: missing closing ): `(`",
+		"targets[2]: checks[2]: equals can only be used with json_path",
+		"rate must not be negative",
+		"stages[0]: duration must be greater than 0",
+		"stages[0]: target must not be negative",
+		"stages: at least one stage must have a target greater than 0",
+		"concurrency must be greater than 0",
+		"timeout must not be negative",
+		"grace must not be negative",
+		"interval must be greater than 0",
+		"precision must be between 1 and 5 significant digits",
+		"percentiles[1]: percentile 0 must be greater than 0 and at most 100",
+		`threshold "p99>" must be in the form metric<value, as in p99<300ms`,
+		`abort "p95>5s for 0s": window "0s" must be a duration greater than 0`,
+		`outputs[0]: format "xml" must be one of text, json`,
+		"store: path must not be empty for the file store",
+		"tags[1]: tag must not be empty",
+	}
+	if got := p.Validate(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = %q, want %q", got, want)
+	}
+
+	p, err = Read("testdata/invalid-journey.yaml")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	want = []string{
+		"targets and journeys can not be used together",
+		"targets[0]: extract can only be used in the steps of a journey",
+		"journeys[0]: name must not be empty",
+		"journeys[0]: at least one step is required",
+		`flow, login: url "{{host}}/login" must be an absolute http(s) url`,
+		"flow, login: extractor token must set exactly one of json, regex or header",
+		`flow, login: extractor id: jsonpath "id" must start with $`,
+	}
+	if got := p.Validate(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = %q, want %q", got, want)
+	}
+
+	p, err = Read("testdata/invalid-feeder.yaml")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	want = []string{
+		"feeders[0]: name must not be empty",
+		"users.v2: name must not have dots, braces or spaces",
+		`users.v2: mode "shuffled" must be one of sequential, random, unique`,
+		"users.v2: open testdata/missing.csv: no such file or directory",
+		"user: {{users.uuid}} is not a column of feeder users",
+		"user: {{orders.id}} uses feeder orders, which is not declared",
+		"user: {{id}} is not a column of a feeder",
+		`coupon: row 2 of feeder codes: header X-Note: invalid value "line\nbreak"`,
+		"code: row 3 of feeder codes: {{codes.code}} is not one of its columns",
+	}
+	if got := p.Validate(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = %q, want %q", got, want)
+	}
+
+	if got := New().Validate(); !reflect.DeepEqual(got, []string{"at least one target or journey is required", "requests must be greater than 0"}) {
+		t.Errorf("Validate() = %q", got)
+	}
+}
@@ -4,19 +4,36 @@ import (
 	"database/sql"
 	"log/slog"
 
-	_ "github.com/mattn/go-sqlite3"
+	_ "modernc.org/sqlite"
 )
 
-// GetDb initializes and returns a new in-memory SQLite database connection.
-// It panics if the database cannot be opened.
+// GetDb initializes and returns a new in-memory SQLite database connection, limited to a
+// single connection, as each one opens a database of its own. It panics if the database
+// cannot be opened.
 
 func GetDb() *sql.DB {
 
-	db, err := sql.Open("sqlite3", "file::memory:?")
+	db, err := sql.Open("sqlite", "file::memory:?_time_format=sqlite")
 
 	if err != nil {
 		slog.Error("pool.GetDb", "msg", err.Error())
 		panic(err)
 	}
+	db.SetMaxOpenConns(1)
 	return db
 }
+
+// OpenDb opens, creating it when it does not exist, the SQLite database in the file at the
+// given path, so the records kept in it outlive the run.
+func OpenDb(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_time_format=sqlite")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 )
@@ -34,27 +35,36 @@ func GetHttpClient() *http.Client {
 
 }
 
-// StressEndpoint sends a request to the given url with the given method and payload
-// and checks the status code of the response.
-//
-// It returns an error if the request fails or the status code is not 200.
-func StressEndpoint(method string, url string, payload string) error {
+// StressEndpoint sends a request to the given url with the given method, payload and
+// headers, waiting at most timeout for the response when it is greater than zero. It
+// returns an error if the request fails or the status code is not one of the expected.
+func StressEndpoint(method string, url string, payload string, header http.Header, timeout time.Duration, expected ...int) error {
 	req, err := http.NewRequest(method, url, strings.NewReader(payload))
 	if err != nil {
 		slog.Error("TestEndpoint", "msg", err.Error())
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	res, err := GetHttpClient().Do(req)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if host := header.Get("Host"); host != "" {
+		req.Host = host
+	}
+	client := GetHttpClient()
+	client.Timeout = timeout
+	res, err := client.Do(req)
 	if err != nil {
 		slog.Error("TestEndpoint Do", "msg", err.Error())
 		return err
 	}
-	if res.StatusCode != 200 {
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if len(expected) == 0 {
+		expected = []int{200}
+	}
+	if !slices.Contains(expected, res.StatusCode) {
 		slog.Error("TestEndpoint StatusCode", "msg", res.StatusCode)
 		return fmt.Errorf("TestEndpoint StatusCode: %d", res.StatusCode)
 	}
-	defer res.Body.Close()
-	io.Copy(io.Discard, res.Body)
 	return nil
 }
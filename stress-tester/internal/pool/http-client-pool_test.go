@@ -6,14 +6,19 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestStressEndpoint(t *testing.T) {
 	type args struct {
-		method  string
-		url     string
-		payload string
+		method   string
+		path     string
+		payload  string
+		header   http.Header
+		timeout  time.Duration
+		expected []int
 	}
 	tests := []struct {
 		name    string
@@ -24,7 +29,7 @@ func TestStressEndpoint(t *testing.T) {
 			name: "Success",
 			args: args{
 				method:  "GET",
-				url:     "http://localhost:8080/hello",
+				path:    "/hello",
 				payload: `{"message":"World"}`,
 			},
 			wantErr: false,
@@ -33,15 +38,80 @@ func TestStressEndpoint(t *testing.T) {
 			name: "Fail",
 			args: args{
 				method:  "GET",
-				url:     "http://localhost:8080/helloo",
+				path:    "/helloo",
 				payload: `{"message":"World"}`,
 			},
 			wantErr: true,
 		},
+		{
+			name: "Header",
+			args: args{
+				method:  "PUT",
+				path:    "/secure",
+				payload: `{"message":"World"}`,
+				header:  http.Header{"X-Api-Key": {"secret"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Missing header",
+			args: args{
+				method:  "PUT",
+				path:    "/secure",
+				payload: `{"message":"World"}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Expected status",
+			args: args{
+				method:   "PUT",
+				path:     "/secure",
+				payload:  `{"message":"World"}`,
+				expected: []int{200, 401},
+			},
+			wantErr: false,
+		},
+		{
+			name: "No default content type",
+			args: args{
+				method:  "POST",
+				path:    "/content-type?want=",
+				payload: `{"message":"World"}`,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Content type header",
+			args: args{
+				method:  "POST",
+				path:    "/content-type?want=text/plain",
+				payload: "World",
+				header:  http.Header{"Content-Type": {"text/plain"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Within the timeout",
+			args: args{
+				method:  "GET",
+				path:    "/slow",
+				timeout: time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Timeout",
+			args: args{
+				method:  "GET",
+				path:    "/slow",
+				timeout: 10 * time.Millisecond,
+			},
+			wantErr: true,
+		},
 	}
-	server := &http.Server{Addr: ":8080"}
-	defer server.Close()
-	http.HandleFunc("GET /hello", func(w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /hello", func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
 			slog.Error("Can't read body", "error", err)
@@ -59,10 +129,28 @@ func TestStressEndpoint(t *testing.T) {
 		}
 		fmt.Fprintf(w, "Hello, %s!", message.Message)
 	})
-	go server.ListenAndServe()
+	mux.HandleFunc("PUT /secure", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("POST /content-type", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != r.URL.Query().Get("want") {
+			http.Error(w, "Unexpected Content-Type", http.StatusUnsupportedMediaType)
+			return
+		}
+		w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := StressEndpoint(tt.args.method, tt.args.url, tt.args.payload); (err != nil) != tt.wantErr {
+			if err := StressEndpoint(tt.args.method, server.URL+tt.args.path, tt.args.payload, tt.args.header, tt.args.timeout, tt.args.expected...); (err != nil) != tt.wantErr {
 
 				t.Errorf("StressEndpoint() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -1,37 +1,346 @@
 package report
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"stress-tester/internal/dto"
+	"stress-tester/internal/stats"
 
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 )
 
+// out is where the text report is printed, the console unless Write is writing a text
+// output to a file.
+var out io.Writer = os.Stdout
+
+// Write writes the summary of a test run in every one of the given outputs, to the
+// console or to the output file, with the percentiles of the settings of the run. It
+// returns the first error found writing or closing a file.
+func Write(summary dto.Summary, settings stats.Settings, outputs []dto.Output) error {
+	for _, o := range outputs {
+		if o.Path == "" {
+			if err := write(os.Stdout, summary, settings, o.Format); err != nil {
+				return err
+			}
+			continue
+		}
+		f, err := os.Create(o.Path)
+		if err != nil {
+			return err
+		}
+		err = write(f, summary, settings, o.Format)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes the summary of a test run to w in the given format.
+func write(w io.Writer, summary dto.Summary, settings stats.Settings, format string) error {
+	if format == "json" {
+		return WriteJSON(w, summary)
+	}
+	out = w
+	ReportSummary(summary, settings)
+	out = os.Stdout
+	return nil
+}
+
+// WriteJSON writes the summary of a test run as indented JSON. Durations are written in
+// nanoseconds.
+func WriteJSON(w io.Writer, summary dto.Summary) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(summary)
+}
+
+// ReportSummary prints the full text report of a test run: the totals, the results of
+// each stage, journey and endpoint, the overall results and the thresholds.
+func ReportSummary(summary dto.Summary, settings stats.Settings) {
+	p := message.NewPrinter(language.English)
+	fmt.Fprintln(out, "Finished ", summary.Requests, " requests for endpoint ", summary.Target, " in ", summary.Elapsed)
+	switch {
+	case summary.Interrupted:
+		fmt.Fprintln(out, "INTERRUPTED, partial results: ", summary.Stopped)
+	case summary.Stopped != "":
+		fmt.Fprintln(out, "Stopped early, partial results: ", summary.Stopped)
+	}
+	if summary.Canceled > 0 {
+		fmt.Fprintln(out, summary.Canceled, " requests in flight canceled when the grace ran out, left out of the results")
+	}
+	if summary.Run != nil {
+		ReportRun(*summary.Run)
+	}
+	if len(summary.Workers) > 0 {
+		ReportWorkers(summary.Workers, summary.Elapsed)
+	}
+	if summary.Schedule != nil {
+		ReportSchedule(*summary.Schedule)
+	}
+	if summary.Ingest != nil {
+		ReportIngest(*summary.Ingest)
+	}
+	for i, stage := range summary.Stages {
+		fmt.Fprintln(out, "Stage ", i+1, ": ", stage.From, " -> ", stage.Stage.Target, " workers over ", stage.Stage.Duration)
+		if len(stage.Red) == 0 {
+			fmt.Fprintln(out, "No requests sent")
+			fmt.Fprintln(out)
+			continue
+		}
+		ReportRed(stage.Red, settings.Percentiles)
+		ReportPercentiles(stage.Percentiles)
+		fmt.Fprintln(out)
+	}
+	if len(summary.Journeys) > 0 {
+		ReportJourneys(summary.Journeys, settings.Percentiles)
+	}
+	for _, endpoint := range summary.Endpoints {
+		if endpoint.Share == 0 {
+			fmt.Fprintln(out, "Step ", endpoint.Endpoint.Name, ": ", endpoint.Endpoint.Method, " ", endpoint.Endpoint.URL)
+		} else {
+			fmt.Fprintln(out, "Endpoint ", endpoint.Endpoint.Name, " (", p.Sprintf("%.1f", endpoint.Share*100), "% of the traffic): ", endpoint.Endpoint.Method, " ", endpoint.Endpoint.URL)
+		}
+		if len(endpoint.Red) == 0 {
+			fmt.Fprintln(out, "No requests sent")
+			fmt.Fprintln(out)
+			continue
+		}
+		ReportRed(endpoint.Red, settings.Percentiles)
+		ReportError(endpoint.Errors)
+		if len(endpoint.NetErrors) > 0 {
+			ReportNetErrors(endpoint.NetErrors)
+		}
+		ReportPercentiles(endpoint.Percentiles)
+		ReportSizes(endpoint.Sizes)
+		fmt.Fprintln(out)
+	}
+	if len(summary.Checks) > 0 {
+		ReportChecks(summary.Checks)
+	}
+	if len(summary.Stages) > 0 || len(summary.Endpoints) > 0 || len(summary.Journeys) > 0 {
+		fmt.Fprintln(out, "Overall")
+	}
+	ReportRed(summary.Red, settings.Percentiles)
+	if len(summary.Red) > 0 {
+		ReportError(summary.Errors)
+		if len(summary.NetErrors) > 0 {
+			ReportNetErrors(summary.NetErrors)
+		}
+		ReportCorrectedPercentiles(summary.Percentiles, summary.Corrected)
+		if summary.Phases.Requests > 0 {
+			ReportPhases(summary.Phases, settings.Percentiles)
+		}
+		ReportSizes(summary.Sizes)
+	}
+	if len(summary.Thresholds) > 0 {
+		ReportThresholds(summary.Thresholds)
+	}
+}
+
 // ReportRed takes a map[string]*dto.ResultRed and prints a report of the
-// results from the test run. The report is sorted by time and formatted in a
+// results from the test run, a row per interval followed by the total of the run, or
+// "No requests" when there are none. The report is sorted by time and formatted in a
 // human-readable format. The columns are:
 //
-// - Rate: The number of requests per second
+// - Time: The offset of the start of the interval from the start of the run
+// - Requests: The number of requests sent during the interval
+// - RPS: The number of requests per second during the interval
 // - Error: The number of requests that had an error
 // - Avg Time: The average time taken for the requests, excluding network errors
 // - Min Time: The minimum time taken for the requests, excluding network errors
 // - Max Time: The maximum time taken for the requests, excluding network errors
+// - P50, P99, ...: The percentiles of the time taken for the requests of the interval
 // - Net Error: The number of requests that had a network error
-func ReportRed(result map[string]*dto.ResultRed) {
-	keys := make([]string, 0, len(result))
-	for k := range result {
-		keys = append(keys, k)
+// - Sent: The bytes sent in the requests, headers and body
+// - Received: The bytes received in the responses, headers and body
+// - MB/s: The megabytes sent and received per second during the interval
+func ReportRed(result map[string]*dto.ResultRed, percentiles []float64) {
+	if len(result) == 0 {
+		fmt.Fprintln(out, "No requests")
+		return
+	}
+	rows := make([]*dto.ResultRed, 0, len(result))
+	for _, r := range result {
+		rows = append(rows, r)
 	}
-	sort.Strings(keys)
+	sort.Slice(rows, func(i, j int) bool { return rows[i].From < rows[j].From })
 	p := message.NewPrinter(language.English)
-	fmt.Printf("%10s\t%10s\t%10s\t%10s\t%10s\t%10s\n", "Rate", "Error", "Avg Time", "Min Time", "Max Time", "Net Error")
-	for _, v := range keys {
-		fmt.Printf("%10s\t%10s\t%10v\t%10v\t%10v\t%10s\n", p.Sprintf("%d", result[v].NumRequestPerSecond), p.Sprintf("%d", result[v].NumRequestWithErrorPerSecond), result[v].AverageDuration/time.Duration(result[v].NumRequestPerSecond-result[v].NumNetworkErrorPerSecond+1), result[v].MinDuration, result[v].MaxDuration, p.Sprintf("%d", result[v].NumNetworkErrorPerSecond))
+	fmt.Fprintf(out, "%10s\t%10s\t%10s\t%10s\t%10s\t%10s\t%10s%s\t%10s\t%12s\t%12s\t%10s\n", "Time", "Requests", "RPS", "Error", "Avg Time", "Min Time", "Max Time", percentileNames(percentiles), "Net Error", "Sent", "Received", "MB/s")
+	total := dto.ResultRed{}
+	for _, r := range rows {
+		fmt.Fprintf(out, "%10v\t%10s\t%10s\t%10s\t%10v\t%10v\t%10v%s\t%10s\t%12s\t%12s\t%10s\n", r.From, p.Sprintf("%d", r.NumRequestPerSecond), p.Sprintf("%.1f", rps(r.NumRequestPerSecond, r.Width)), p.Sprintf("%d", r.NumRequestWithErrorPerSecond), average(*r), r.MinDuration, r.MaxDuration, percentileDurations(r.Percentiles), p.Sprintf("%d", r.NumNetworkErrorPerSecond), p.Sprintf("%d", r.BytesSent), p.Sprintf("%d", r.BytesReceived), p.Sprintf("%.2f", throughput(r.BytesSent+r.BytesReceived, r.Width)))
+		if responses(*r) > 0 && (responses(total) == 0 || r.MinDuration < total.MinDuration) {
+			total.MinDuration = r.MinDuration
+		}
+		total.NumRequestPerSecond += r.NumRequestPerSecond
+		total.NumRequestWithErrorPerSecond += r.NumRequestWithErrorPerSecond
+		total.NumNetworkErrorPerSecond += r.NumNetworkErrorPerSecond
+		total.AverageDuration += r.AverageDuration
+		total.MaxDuration = max(total.MaxDuration, r.MaxDuration)
+		total.BytesSent += r.BytesSent
+		total.BytesReceived += r.BytesReceived
+	}
+	if len(rows) > 1 {
+		width := rows[len(rows)-1].From + rows[len(rows)-1].Width - rows[0].From
+		blank := strings.Repeat(fmt.Sprintf("\t%10s", ""), len(percentiles))
+		fmt.Fprintf(out, "%10s\t%10s\t%10s\t%10s\t%10v\t%10v\t%10v%s\t%10s\t%12s\t%12s\t%10s\n", "Total", p.Sprintf("%d", total.NumRequestPerSecond), p.Sprintf("%.1f", rps(total.NumRequestPerSecond, width)), p.Sprintf("%d", total.NumRequestWithErrorPerSecond), average(total), total.MinDuration, total.MaxDuration, blank, p.Sprintf("%d", total.NumNetworkErrorPerSecond), p.Sprintf("%d", total.BytesSent), p.Sprintf("%d", total.BytesReceived), p.Sprintf("%.2f", throughput(total.BytesSent+total.BytesReceived, width)))
+	}
+}
+
+// rps returns the number of requests per second of the given requests sent over width.
+func rps(requests int, width time.Duration) float64 {
+	if width <= 0 {
+		return 0
+	}
+	return float64(requests) / width.Seconds()
+}
+
+// throughput returns the megabytes per second of the given bytes transferred over width.
+func throughput(bytes int64, width time.Duration) float64 {
+	if width <= 0 {
+		return 0
+	}
+	return float64(bytes) / 1e6 / width.Seconds()
+}
+
+// average returns the average duration of the requests of the interval that got a
+// response, zero when none did.
+func average(r dto.ResultRed) time.Duration {
+	n := responses(r)
+	if n == 0 {
+		return 0
+	}
+	return r.AverageDuration / time.Duration(n)
+}
+
+// responses returns the number of requests of the interval that got a response.
+func responses(r dto.ResultRed) int {
+	return r.NumRequestPerSecond - r.NumNetworkErrorPerSecond
+}
+
+// ReportRuns prints a line per run kept in a results database with its id, when it
+// started, how long it took, the version of the tool, the git SHA of the system under test,
+// its tags and its targets.
+func ReportRuns(runs []dto.Run) {
+	fmt.Fprintf(out, "%-20s\t%-25s\t%12s\t%-10s\t%-10s\t%s\n", "Run", "Started", "Duration", "Version", "Git SHA", "Tags / Targets")
+	for _, r := range runs {
+		duration := "-"
+		if !r.FinishedAt.IsZero() {
+			duration = r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(out, "%-20s\t%-25s\t%12s\t%-10s\t%-10s\t%s\n", r.ID, r.StartedAt.Format(time.RFC3339), duration, r.Version, r.GitSHA, strings.Join(slices.Concat(r.Tags, r.Targets), ", "))
+	}
+}
+
+// ReportRun prints the id of a run kept in a results database, the file of the database,
+// and the labels of the run, when it has any.
+func ReportRun(run dto.Run) {
+	fmt.Fprintln(out, "Run ", run.ID, " saved to ", run.Path)
+	if run.GitSHA != "" || len(run.Tags) > 0 {
+		fmt.Fprintln(out, "Git SHA: ", run.GitSHA, " Tags: ", strings.Join(run.Tags, ", "))
+	}
+}
+
+// ReportWorkers prints a summary line per worker with the number of requests it sent and
+// the time it spent waiting for responses, also as a share of the elapsed time of the run.
+func ReportWorkers(workers []dto.ResultWorker, elapsed time.Duration) {
+	p := message.NewPrinter(language.English)
+	fmt.Fprintf(out, "%10s\t%10s\t%10s\t%10s\n", "Worker", "Requests", "Busy", "Busy %")
+	for _, w := range workers {
+		busy := 0.0
+		if elapsed > 0 {
+			busy = float64(w.Busy) / float64(elapsed) * 100
+		}
+		fmt.Fprintf(out, "%10d\t%10s\t%10v\t%10s\n", w.ID, p.Sprintf("%d", w.Requests), w.Busy.Round(time.Microsecond), p.Sprintf("%.1f", busy))
 	}
+	fmt.Fprintln(out)
+}
+
+// ReportJourneys prints a line per journey with its share of the traffic, how many times
+// it ran and failed, a run failing when one of its steps got no response, failed one of
+// its checks or did not have a value to extract, and the average, min, max and
+// percentiles of the durations of the whole runs.
+func ReportJourneys(journeys []dto.ResultJourney, percentiles []float64) {
+	p := message.NewPrinter(language.English)
+	fmt.Fprintf(out, "%-20s\t%10s\t%10s\t%10s\t%10s\t%10s\t%10s%s\n", "Journey", "Share %", "Runs", "Failed", "Avg Time", "Min Time", "Max Time", percentileNames(percentiles))
+	for _, j := range journeys {
+		fmt.Fprintf(out, "%-20s\t%10s\t%10s\t%10s\t%10v\t%10v\t%10v%s\n", j.Name, p.Sprintf("%.1f", j.Share*100), p.Sprintf("%d", j.Runs), p.Sprintf("%d", j.Failed), j.AverageDuration, j.MinDuration, j.MaxDuration, percentileDurations(j.Percentiles))
+	}
+	fmt.Fprintln(out)
+}
+
+// ReportChecks prints a line per check of the endpoints with how many responses passed and
+// failed it and the share of the responses that passed it.
+func ReportChecks(checks []dto.ResultCheck) {
+	p := message.NewPrinter(language.English)
+	fmt.Fprintf(out, "%-20s\t%-30s\t%10s\t%10s\t%10s\n", "Endpoint", "Check", "Passed", "Failed", "Pass %")
+	for _, c := range checks {
+		rate := 0.0
+		if total := c.Passed + c.Failed; total > 0 {
+			rate = float64(c.Passed) / float64(total) * 100
+		}
+		fmt.Fprintf(out, "%-20s\t%-30s\t%10s\t%10s\t%10s\n", c.Endpoint, c.Check, p.Sprintf("%d", c.Passed), p.Sprintf("%d", c.Failed), p.Sprintf("%.2f", rate))
+	}
+	fmt.Fprintln(out)
+}
+
+// ReportThresholds prints a line per threshold with the value it was compared with and
+// whether it passed, followed by the result of the run: PASS when all of them passed,
+// FAIL otherwise.
+func ReportThresholds(thresholds []dto.ResultThreshold) {
+	fmt.Fprintf(out, "\n%-20s\t%10s\t%6s\n", "Threshold", "Actual", "Result")
+	failed := 0
+	for _, t := range thresholds {
+		result := "PASS"
+		if !t.Passed {
+			result = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(out, "%-20s\t%10s\t%6s\n", t.Threshold, t.Actual, result)
+	}
+	if failed > 0 {
+		fmt.Fprintln(out, "\nFAIL: ", failed, " of ", len(thresholds), " thresholds breached")
+		return
+	}
+	fmt.Fprintln(out, "\nPASS: all ", len(thresholds), " thresholds passed")
+}
+
+// ReportIngest prints how the records of a run made their way to the store: how many were
+// written, in how many batches and for how long, how full the buffer got, and whether the
+// requests ever had to wait for room in it, which would have throttled the load.
+func ReportIngest(result dto.ResultIngest) {
+	p := message.NewPrinter(language.English)
+	fmt.Fprintf(out, "%10s\t%10s\t%10s\t%12s\t%10s\t%10s\t%10s\t%10s\n", "Records", "Batches", "Max Batch", "Writing", "Max Queued", "Buffer", "Failed", "Dropped")
+	fmt.Fprintf(out, "%10s\t%10s\t%10s\t%12s\t%10s\t%10s\t%10s\t%10s\n", p.Sprintf("%d", result.Records), p.Sprintf("%d", result.Batches), p.Sprintf("%d", result.MaxBatch), result.Writing.Round(time.Microsecond), p.Sprintf("%d", result.MaxQueued), p.Sprintf("%d", result.Buffer), p.Sprintf("%d", result.Failed), p.Sprintf("%d", result.Dropped))
+	if result.Blocked == 0 {
+		fmt.Fprintln(out, "Recording never made a request wait")
+	} else {
+		p.Fprintf(out, "Recording made %d requests wait %v in total for room in the buffer\n", result.Blocked, result.Waited)
+	}
+	fmt.Fprintln(out)
+}
+
+// ReportSchedule prints how the requests scheduled by a constant arrival rate run were
+// dispatched: how many went out on time, how many were sent late because the in-flight
+// cap was hit, and how many were dropped because no slot freed up before the next one
+// was due.
+func ReportSchedule(result dto.ResultSchedule) {
+	p := message.NewPrinter(language.English)
+	fmt.Fprintf(out, "%10s\t%10s\t%10s\t%10s\t%10s\t%10s\n", "Target/s", "Max Flight", "Scheduled", "Sent", "Late", "Dropped")
+	fmt.Fprintf(out, "%10s\t%10s\t%10s\t%10s\t%10s\t%10s\n\n", p.Sprintf("%.2f", result.Rate), p.Sprintf("%d", result.MaxInFlight), p.Sprintf("%d", result.Scheduled), p.Sprintf("%d", result.Sent), p.Sprintf("%d", result.Late), p.Sprintf("%d", result.Dropped))
 }
 
 // ReportError takes a map[int]*dto.ResultError and prints a report of the number of times each status code was encountered
@@ -43,20 +352,113 @@ func ReportError(errors map[int]*dto.ResultError) {
 	}
 	sort.Ints(keys)
 	p := message.NewPrinter(language.English)
-	fmt.Printf("\n%-7s\t%10s\n", "Status", "# Responses")
+	fmt.Fprintf(out, "\n%-7s\t%10s\n", "Status", "# Responses")
 	for _, v := range keys {
-		fmt.Printf("%-7s\t%10s\n", p.Sprintf("%d", v), p.Sprintf("%d", errors[v].NumRequestWithErrorPerSecond))
+		fmt.Fprintf(out, "%-7s\t%10s\n", p.Sprintf("%d", v), p.Sprintf("%d", errors[v].NumRequestWithErrorPerSecond))
+	}
+}
+
+// ReportNetErrors prints the number of network errors, the requests that got no response,
+// of each category, along with the message of one of them as a sample. The report is
+// sorted by category.
+func ReportNetErrors(errors map[dto.NetError]*dto.ResultNetError) {
+	keys := make([]dto.NetError, 0, len(errors))
+	for k := range errors {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	p := message.NewPrinter(language.English)
+	fmt.Fprintf(out, "\n%-20s\t%10s\t%s\n", "Network Error", "# Requests", "Sample")
+	for _, k := range keys {
+		fmt.Fprintf(out, "%-20s\t%10s\t%s\n", k, p.Sprintf("%d", errors[k].Requests), errors[k].Sample)
 	}
 }
 
 // ReportPercentiles prints the percentiles for a given dto.Percentiles
-// to the console, in a human-readable format.
+// to the console, in a human-readable format, between the min and the max,
+// followed by the mean and the standard deviation.
 func ReportPercentiles(perc dto.Percentiles) {
-	fmt.Printf("\n%-10s\t%10s\n", "Percentile", "Duration")
-	fmt.Printf("%-10s\t%10v\n", "P10", perc.P10)
-	fmt.Printf("%-10s\t%10v\n", "P25", perc.P25)
-	fmt.Printf("%-10s\t%10v\n", "P50", perc.P50)
-	fmt.Printf("%-10s\t%10v\n", "P75", perc.P75)
-	fmt.Printf("%-10s\t%10v\n", "P90", perc.P90)
-	fmt.Printf("%-10s\t%10v\n", "P99", perc.P99)
+	fmt.Fprintf(out, "\n%-10s\t%10s\n", "Percentile", "Duration")
+	fmt.Fprintf(out, "%-10s\t%10v\n", "Min", perc.Min)
+	for _, v := range perc.Values {
+		fmt.Fprintf(out, "%-10s\t%10v\n", percentileName(v.Percentile), v.Duration)
+	}
+	fmt.Fprintf(out, "%-10s\t%10v\n", "Max", perc.Max)
+	fmt.Fprintf(out, "%-10s\t%10v\n", "Mean", perc.Mean)
+	fmt.Fprintf(out, "%-10s\t%10v\n", "StdDev", perc.StdDev)
+}
+
+// ReportSizes prints the sizes of the responses, headers and body, in bytes, at each
+// percentile, between the smallest and the largest, followed by the mean size.
+func ReportSizes(sizes dto.Sizes) {
+	p := message.NewPrinter(language.English)
+	fmt.Fprintf(out, "\n%-10s\t%12s\n", "Size", "Bytes")
+	fmt.Fprintf(out, "%-10s\t%12s\n", "Min", p.Sprintf("%d", sizes.Min))
+	for _, v := range sizes.Values {
+		fmt.Fprintf(out, "%-10s\t%12s\n", percentileName(v.Percentile), p.Sprintf("%d", v.Bytes))
+	}
+	fmt.Fprintf(out, "%-10s\t%12s\n", "Max", p.Sprintf("%d", sizes.Max))
+	fmt.Fprintf(out, "%-10s\t%12s\n", "Mean", p.Sprintf("%d", sizes.Mean))
+}
+
+// ReportCorrectedPercentiles prints the raw percentiles, measured from when each request
+// was sent, side by side with the ones corrected for coordinated omission, measured from
+// when each request was meant to be sent, in the same format as ReportPercentiles.
+func ReportCorrectedPercentiles(raw, corrected dto.Percentiles) {
+	fmt.Fprintf(out, "\n%-10s\t%10s\t%10s\n", "Percentile", "Duration", "Corrected")
+	fmt.Fprintf(out, "%-10s\t%10v\t%10v\n", "Min", raw.Min, corrected.Min)
+	for i, v := range raw.Values {
+		var c time.Duration
+		if i < len(corrected.Values) {
+			c = corrected.Values[i].Duration
+		}
+		fmt.Fprintf(out, "%-10s\t%10v\t%10v\n", percentileName(v.Percentile), v.Duration, c)
+	}
+	fmt.Fprintf(out, "%-10s\t%10v\t%10v\n", "Max", raw.Max, corrected.Max)
+	fmt.Fprintf(out, "%-10s\t%10v\t%10v\n", "Mean", raw.Mean, corrected.Mean)
+	fmt.Fprintf(out, "%-10s\t%10v\t%10v\n", "StdDev", raw.StdDev, corrected.StdDev)
+}
+
+// ReportPhases prints a line per phase of the requests with how many requests went through
+// it and the min, percentiles, max and mean of its durations, followed by how many
+// requests reused a connection and how many opened a new one.
+func ReportPhases(result dto.ResultPhases, percentiles []float64) {
+	p := message.NewPrinter(language.English)
+	fmt.Fprintf(out, "\n%-10s\t%10s\t%10s%s\t%10s\t%10s\n", "Phase", "Requests", "Min", percentileNames(percentiles), "Max", "Mean")
+	for _, phase := range result.Phases {
+		fmt.Fprintf(out, "%-10s\t%10s\t%10v%s\t%10v\t%10v\n", phase.Phase, p.Sprintf("%d", phase.Requests), phase.Percentiles.Min, percentileDurations(phase.Percentiles), phase.Percentiles.Max, phase.Percentiles.Mean)
+	}
+	p.Fprintf(out, "\nConnections: %d reused, %d new (%.1f%% reused)\n", result.Reused, result.Requests-result.Reused, share(result.Reused, result.Requests))
+}
+
+// share returns n as a percentage of total, zero when total is zero.
+func share(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+// percentileName returns the name of the percentile in the report, as in P99.9.
+func percentileName(p float64) string {
+	return "P" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// percentileNames returns the names of the percentiles as columns of a row of the report.
+func percentileNames(percentiles []float64) string {
+	var b strings.Builder
+	for _, p := range percentiles {
+		fmt.Fprintf(&b, "\t%10s", percentileName(p))
+	}
+	return b.String()
+}
+
+// percentileDurations returns the durations at the percentiles as columns of a row of the
+// report.
+func percentileDurations(perc dto.Percentiles) string {
+	var b strings.Builder
+	for _, v := range perc.Values {
+		fmt.Fprintf(&b, "\t%10v", v.Duration)
+	}
+	return b.String()
 }
@@ -0,0 +1,67 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"stress-tester/internal/dto"
+	"stress-tester/internal/stats"
+)
+
+func TestReportSummary(t *testing.T) {
+	settings := stats.Settings{Percentiles: []float64{50, 99}}
+	tests := []struct {
+		name    string
+		summary dto.Summary
+		want    []string
+	}{
+		{
+			name:    "Empty",
+			summary: dto.Summary{Target: "http://localhost", Interrupted: true, Stopped: "interrupted"},
+			want:    []string{"Finished  0  requests", "INTERRUPTED", "No requests"},
+		},
+		{
+			name: "EmptyEndpoint",
+			summary: dto.Summary{
+				Target:     "a, b",
+				Endpoints:  []dto.ResultEndpoint{{Endpoint: dto.Endpoint{Name: "a"}, Share: 1}},
+				Thresholds: []dto.ResultThreshold{{Threshold: "status_5xx<1", Actual: "0", Passed: true}},
+			},
+			want: []string{"No requests sent", "Overall", "No requests", "PASS"},
+		},
+		{
+			name: "Requests",
+			summary: dto.Summary{
+				Target:   "http://localhost",
+				Requests: 1,
+				Red: map[string]*dto.ResultRed{"0s": {
+					From:                time.Duration(0),
+					Width:               time.Second,
+					NumRequestPerSecond: 1,
+					MinDuration:         time.Millisecond,
+					MaxDuration:         time.Millisecond,
+					AverageDuration:     time.Millisecond,
+					Percentiles:         dto.Percentiles{Values: []dto.Percentile{{Percentile: 50, Duration: time.Millisecond}, {Percentile: 99, Duration: time.Millisecond}}},
+				}},
+				Errors: map[int]*dto.ResultError{200: {NumRequestWithErrorPerSecond: 1}},
+			},
+			want: []string{"P50", "P99", "Corrected"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			out = &b
+			defer func() { out = os.Stdout }()
+			ReportSummary(tt.summary, settings)
+			for _, w := range tt.want {
+				if !strings.Contains(b.String(), w) {
+					t.Errorf("ReportSummary() = %q, want it to contain %q", b.String(), w)
+				}
+			}
+		})
+	}
+}
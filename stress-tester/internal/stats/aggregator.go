@@ -0,0 +1,283 @@
+package stats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+// Settings are how an Aggregator summarizes the requests of a run: the start of the run,
+// the width of the intervals of the results over time, zero for a single interval, the
+// precision of the histograms in significant digits and the percentiles reported.
+type Settings struct {
+	Start       time.Time
+	Width       time.Duration
+	Digits      int
+	Percentiles []float64
+}
+
+// phaseNames are the names of the phases of the requests, in the order of dto.ResultPhases.
+var phaseNames = []string{"DNS", "Connect", "TLS", "TTFB", "Transfer"}
+
+// Aggregator summarizes the requests of a run as they are added, one at a time, without
+// keeping them: the results of each interval, the status codes, the network errors by
+// category, and the histograms of the durations, of the phases and of the sizes of the
+// responses. The zero value is not usable, use NewAggregator.
+type Aggregator struct {
+	settings  Settings
+	red       map[string]*dto.ResultRed
+	intervals map[string]*Histogram
+	errors    map[int]*dto.ResultError
+	netErrors map[dto.NetError]*dto.ResultNetError
+	statuses  map[string]int
+	durations *Histogram
+	phases    []*Histogram
+	sizes     *Histogram
+	count     int
+	failed    int
+	responses int
+	reused    int
+	last      time.Time
+}
+
+// NewAggregator creates an empty aggregator with the given settings.
+func NewAggregator(settings Settings) *Aggregator {
+	a := &Aggregator{
+		settings:  settings,
+		red:       map[string]*dto.ResultRed{},
+		intervals: map[string]*Histogram{},
+		errors:    map[int]*dto.ResultError{},
+		netErrors: map[dto.NetError]*dto.ResultNetError{},
+		statuses:  map[string]int{},
+		durations: NewHistogram(settings.Digits),
+		sizes:     NewHistogram(settings.Digits),
+	}
+	for range phaseNames {
+		a.phases = append(a.phases, NewHistogram(settings.Digits))
+	}
+	return a
+}
+
+// Add adds a request to the summary.
+func (a *Aggregator) Add(rec *dto.Red) {
+	a.count++
+	if rec.FailedCheck != "" {
+		a.failed++
+	}
+	if a.errors[rec.StatusCode] == nil {
+		a.errors[rec.StatusCode] = &dto.ResultError{ErrorType: rec.StatusCode}
+	}
+	a.errors[rec.StatusCode].NumRequestWithErrorPerSecond++
+	if rec.ReceivedAt.After(a.last) {
+		a.last = rec.ReceivedAt
+	}
+
+	var from time.Duration
+	if a.settings.Width > 0 {
+		from = max(rec.SentAt.Sub(a.settings.Start), 0) / a.settings.Width * a.settings.Width
+	}
+	s := from.String()
+	if a.red[s] == nil {
+		a.red[s] = &dto.ResultRed{From: from, Width: a.settings.Width}
+		a.intervals[s] = NewHistogram(a.settings.Digits)
+	}
+	r := a.red[s]
+	r.NumRequestPerSecond++
+	r.BytesSent += rec.BytesSent
+	r.BytesReceived += rec.BytesReceived
+	if rec.StatusCode == -1 {
+		r.NumNetworkErrorPerSecond++
+		category := rec.NetError
+		if category == "" {
+			category = dto.NetErrorOther
+		}
+		if a.netErrors[category] == nil {
+			a.netErrors[category] = &dto.ResultNetError{Category: category, Sample: rec.ErrorMessage}
+		}
+		a.netErrors[category].Requests++
+		return
+	}
+	if rec.FailedCheck != "" {
+		r.NumRequestWithErrorPerSecond++
+	}
+	d := rec.ReceivedAt.Sub(rec.SentAt)
+	r.AverageDuration += d
+	if d > r.MaxDuration {
+		r.MaxDuration = d
+	}
+	if d < r.MinDuration || r.NumRequestPerSecond-r.NumNetworkErrorPerSecond == 1 {
+		r.MinDuration = d
+	}
+	a.intervals[s].Record(rec.Duration)
+
+	code := strconv.Itoa(rec.StatusCode)
+	a.statuses["status_"+code]++
+	a.statuses["status_"+code[:1]+"xx"]++
+	a.durations.Record(rec.Duration)
+
+	a.responses++
+	if rec.Phases.Reused {
+		a.reused++
+	}
+	for i, d := range []time.Duration{rec.Phases.DNS, rec.Phases.Connect, rec.Phases.TLS} {
+		if d > 0 {
+			a.phases[i].Record(d)
+		}
+	}
+	a.phases[3].Record(rec.Phases.TTFB)
+	a.phases[4].Record(rec.Phases.Transfer)
+	a.sizes.Record(time.Duration(rec.BytesReceived))
+}
+
+// Count returns the number of requests added.
+func (a *Aggregator) Count() int {
+	return a.count
+}
+
+// Red returns the results of each interval, keyed by the offset of its start from the
+// start of the run, the last interval only spanning up to the last response. See
+// CalculateRed.
+func (a *Aggregator) Red() map[string]*dto.ResultRed {
+	var last *dto.ResultRed
+	for s, h := range a.intervals {
+		a.red[s].Percentiles = h.Percentiles(a.settings.Percentiles)
+		if last == nil || a.red[s].From > last.From {
+			last = a.red[s]
+		}
+	}
+	if last != nil && a.settings.Width > 0 {
+		last.Width = min(max(a.last.Sub(a.settings.Start)-last.From, 0), a.settings.Width)
+	}
+	return a.red
+}
+
+// Errors returns the number of requests of each status code. See CalculateErrors.
+func (a *Aggregator) Errors() map[int]*dto.ResultError {
+	return a.errors
+}
+
+// NetErrors returns the network errors, the requests that got no response, by category,
+// with how many requests failed with an error of the category and the message of the
+// first of them.
+func (a *Aggregator) NetErrors() map[dto.NetError]*dto.ResultNetError {
+	return a.netErrors
+}
+
+// Percentiles returns the distribution of the durations of the requests that got a
+// response. See CalculatePercentile.
+func (a *Aggregator) Percentiles() dto.Percentiles {
+	return a.durations.Percentiles(a.settings.Percentiles)
+}
+
+// Phases returns the distribution of the durations of each phase of the requests that got
+// a response, and how many of them reused a connection. The DNS, connect and TLS phases
+// only count the requests that went through them, as a reused connection skips them all.
+func (a *Aggregator) Phases() dto.ResultPhases {
+	result := dto.ResultPhases{Requests: a.responses, Reused: a.reused}
+	for i, h := range a.phases {
+		result.Phases = append(result.Phases, dto.ResultPhase{Phase: phaseNames[i], Requests: h.Count(), Percentiles: h.Percentiles(a.settings.Percentiles)})
+	}
+	return result
+}
+
+// Sizes returns the distribution of the sizes of the responses, headers and body. Network
+// errors, which got no response, are left out.
+func (a *Aggregator) Sizes() dto.Sizes {
+	result := dto.Sizes{Values: make([]dto.Size, len(a.settings.Percentiles)), Min: int64(a.sizes.Min()), Max: int64(a.sizes.Max()), Mean: int64(a.sizes.Mean())}
+	for i, p := range a.settings.Percentiles {
+		result.Values[i] = dto.Size{Percentile: p, Bytes: int64(a.sizes.Percentile(p))}
+	}
+	return result
+}
+
+// Thresholds compares the requests of a run, which took elapsed, with each of the
+// thresholds. See EvaluateThresholds.
+func (a *Aggregator) Thresholds(elapsed time.Duration, thresholds []dto.Threshold) []dto.ResultThreshold {
+	results := make([]dto.ResultThreshold, len(thresholds))
+	for i, t := range thresholds {
+		var actual float64
+		var text string
+		switch {
+		case t.Metric == "error_rate":
+			actual = share(a.failed, a.count)
+			text = fmt.Sprintf("%.2f%%", actual*100)
+		case t.Metric == "rps":
+			if elapsed > 0 {
+				actual = float64(a.count) / elapsed.Seconds()
+			}
+			text = fmt.Sprintf("%.2f", actual)
+		case t.Metric == "requests":
+			actual = float64(a.count)
+			text = fmt.Sprint(a.count)
+		case t.Metric == "errors":
+			actual = float64(a.failed)
+			text = fmt.Sprint(a.failed)
+		case strings.HasPrefix(t.Metric, "status_"):
+			actual = float64(a.statuses[t.Metric])
+			text = fmt.Sprint(a.statuses[t.Metric])
+			if t.Rate {
+				actual = share(a.statuses[t.Metric], a.count)
+				text = fmt.Sprintf("%.2f%%", actual*100)
+			}
+		default:
+			var d time.Duration
+			switch t.Metric {
+			case "avg":
+				d = a.durations.Mean()
+			case "min":
+				d = a.durations.Min()
+			case "max":
+				d = a.durations.Max()
+			default:
+				d = a.durations.Percentile(t.Percentile)
+			}
+			actual = float64(d)
+			text = d.String()
+		}
+		results[i] = dto.ResultThreshold{Threshold: t.Text, Actual: text, Passed: compare(actual, t.Op, t.Value)}
+	}
+	return results
+}
+
+// JourneyAggregator summarizes the runs of a journey as they are added, one at a time,
+// without keeping them. The zero value is not usable, use NewJourneyAggregator.
+type JourneyAggregator struct {
+	percentiles []float64
+	result      dto.ResultJourney
+	durations   *Histogram
+}
+
+// NewJourneyAggregator creates an empty journey aggregator with histograms of the given
+// precision, in significant digits, reporting the given percentiles.
+func NewJourneyAggregator(digits int, percentiles []float64) *JourneyAggregator {
+	return &JourneyAggregator{percentiles: percentiles, durations: NewHistogram(digits)}
+}
+
+// Add adds a run of the journey to the summary.
+func (a *JourneyAggregator) Add(rec *dto.JourneyRed) {
+	a.result.Name = rec.Name
+	a.result.Runs++
+	if rec.Failed {
+		a.result.Failed++
+	}
+	a.durations.Record(rec.Duration)
+}
+
+// Count returns the number of runs added.
+func (a *JourneyAggregator) Count() int {
+	return a.result.Runs
+}
+
+// Result returns the summary of the runs of the journey: the number of runs and failed
+// runs, and the average, min, max and percentiles of their durations.
+func (a *JourneyAggregator) Result() dto.ResultJourney {
+	result := a.result
+	result.AverageDuration = a.durations.Mean()
+	result.MinDuration = a.durations.Min()
+	result.MaxDuration = a.durations.Max()
+	result.Percentiles = a.durations.Percentiles(a.percentiles)
+	return result
+}
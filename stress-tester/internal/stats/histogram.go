@@ -0,0 +1,312 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+// DefaultDigits is the default precision of the histograms, in significant digits.
+const DefaultDigits = 3
+
+// DefaultPercentiles are the percentiles of the report when none are asked for.
+var DefaultPercentiles = []float64{50, 90, 95, 99, 99.9, 99.99}
+
+// Histogram records durations in buckets that get wider as the durations grow, in the
+// manner of an HDR histogram, within 10^-digits of the recorded durations. The zero value
+// is not usable, use NewHistogram.
+type Histogram struct {
+	digits int
+	// shift is such that durations below 1<<shift get a bucket of their own, and wider
+	// durations a bucket 1<<(shift-1) times narrower than them.
+	shift  int
+	counts map[int]int64
+	count  int64
+	sum    time.Duration
+	// squares is the sum of the squares of the durations, in seconds.
+	squares float64
+	min     time.Duration
+	max     time.Duration
+}
+
+// NewHistogram creates an empty histogram with the given precision, in significant
+// digits between 1 and 5.
+func NewHistogram(digits int) *Histogram {
+	digits = min(max(digits, 1), 5)
+	largest := int64(2)
+	for range digits {
+		largest *= 10
+	}
+	return &Histogram{digits: digits, shift: bits.Len64(uint64(largest - 1)), counts: map[int]int64{}}
+}
+
+// Record adds a duration to the histogram. Negative durations are recorded as zero.
+func (h *Histogram) Record(d time.Duration) {
+	d = max(d, 0)
+	h.counts[h.index(d)]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	h.max = max(h.max, d)
+	h.count++
+	h.sum += d
+	h.squares += d.Seconds() * d.Seconds()
+}
+
+// RecordCorrected adds a duration to the histogram along with the requests that would
+// have been sent every expected interval while waiting for it, correcting the coordinated
+// omission of a closed model.
+func (h *Histogram) RecordCorrected(d, expected time.Duration) {
+	h.Record(d)
+	if expected <= 0 {
+		return
+	}
+	for missing := d - expected; missing >= expected; missing -= expected {
+		h.Record(missing)
+	}
+}
+
+// Merge adds every duration recorded in other to the histogram. Both must have the same
+// precision.
+func (h *Histogram) Merge(other *Histogram) error {
+	if other.digits != h.digits {
+		return fmt.Errorf("can not merge a histogram of %d digits into one of %d", other.digits, h.digits)
+	}
+	if other.count == 0 {
+		return nil
+	}
+	for i, n := range other.counts {
+		h.counts[i] += n
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	h.max = max(h.max, other.max)
+	h.count += other.count
+	h.sum += other.sum
+	h.squares += other.squares
+	return nil
+}
+
+// Count returns the number of durations recorded.
+func (h *Histogram) Count() int {
+	return int(h.count)
+}
+
+// Min returns the shortest duration recorded, zero when there are none.
+func (h *Histogram) Min() time.Duration {
+	return h.min
+}
+
+// Max returns the longest duration recorded, zero when there are none.
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+// Mean returns the average of the durations recorded, zero when there are none.
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+// StdDev returns the standard deviation of the durations recorded, zero when there are
+// none.
+func (h *Histogram) StdDev() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	mean := h.Mean().Seconds()
+	variance := max(h.squares/float64(h.count)-mean*mean, 0)
+	return time.Duration(math.Sqrt(variance) * float64(time.Second))
+}
+
+// Percentile returns the duration below which p percent of the durations recorded fall,
+// p being between 0 and 100, or zero when there are none. It is the nearest rank
+// percentile: of n durations sorted, the one at rank p*n/100 rounded up, counting from
+// 1, so that P50 of 10 durations is the 5th one and P99.9 of 1000 durations the 999th.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	r := rank(p, h.count)
+	if r == 1 {
+		return h.min
+	}
+	indexes := make([]int, 0, len(h.counts))
+	for i := range h.counts {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	var seen int64
+	for _, i := range indexes {
+		seen += h.counts[i]
+		if seen >= r {
+			return min(max(h.highest(i), h.min), h.max)
+		}
+	}
+	return h.max
+}
+
+// rank returns the nearest rank of the percentile p of n values sorted, counting from 1,
+// between 1 and n. The rank is rounded down when it is within a rounding error of an
+// integer, as in 99.9*1000/100.
+func rank(p float64, n int64) int64 {
+	r := int64(math.Ceil(p*float64(n)/100 - 1e-9))
+	return min(max(r, 1), n)
+}
+
+// Percentiles returns the given percentiles of the histogram, along with the shortest,
+// longest, mean and standard deviation of the durations recorded.
+func (h *Histogram) Percentiles(percentiles []float64) dto.Percentiles {
+	result := dto.Percentiles{Values: make([]dto.Percentile, len(percentiles)), Min: h.Min(), Max: h.Max(), Mean: h.Mean(), StdDev: h.StdDev()}
+	for i, p := range percentiles {
+		result.Values[i] = dto.Percentile{Percentile: p, Duration: h.Percentile(p)}
+	}
+	return result
+}
+
+// index returns the bucket of the duration. Durations below 1<<shift have a bucket each,
+// the wider ones are grouped in buckets of 1<<e durations, e being how many bits they have
+// past shift, the first one of each group of buckets of the same width following the last
+// one of the previous group.
+func (h *Histogram) index(d time.Duration) int {
+	v := uint64(d)
+	e := max(bits.Len64(v)-h.shift, 0)
+	if e == 0 {
+		return int(v)
+	}
+	half := 1 << (h.shift - 1)
+	return 1<<h.shift + (e-1)*half + int(v>>e) - half
+}
+
+// highest returns the longest duration that falls in the bucket at index i.
+func (h *Histogram) highest(i int) time.Duration {
+	if i < 1<<h.shift {
+		return time.Duration(i)
+	}
+	half := 1 << (h.shift - 1)
+	e := (i-1<<h.shift)/half + 1
+	v := uint64((i-1<<h.shift)%half+half) << e
+	return time.Duration(v + 1<<e - 1)
+}
+
+// Recorder keeps the histograms of the durations of a run, raw and corrected for
+// coordinated omission, for each interval of the given width. Each worker records in its
+// own recorder, merged at the end of the run. It is safe for concurrent use.
+type Recorder struct {
+	start     time.Time
+	width     time.Duration
+	digits    int
+	backfill  bool
+	mu        sync.Mutex
+	intervals map[time.Duration]*Histogram
+	corrected map[time.Duration]*Histogram
+	sum       time.Duration
+	count     int
+}
+
+// NewRecorder creates an empty recorder for a run started at start, with intervals of the
+// given width and histograms of the given precision, backfilling the corrected histograms
+// when backfill is set. The width must be greater than zero.
+func NewRecorder(start time.Time, width time.Duration, digits int, backfill bool) *Recorder {
+	return &Recorder{
+		start:     start,
+		width:     width,
+		digits:    digits,
+		backfill:  backfill,
+		intervals: map[time.Duration]*Histogram{},
+		corrected: map[time.Duration]*Histogram{},
+	}
+}
+
+// Record adds the duration of the request to the histograms of its interval. Network
+// errors, which got no response, are left out.
+func (r *Recorder) Record(red *dto.Red) {
+	if red.StatusCode == -1 {
+		return
+	}
+	from := max(red.SentAt.Sub(r.start), 0) / r.width * r.width
+	corrected := red.Duration
+	if !red.IntendedAt.IsZero() {
+		corrected += max(red.SentAt.Sub(red.IntendedAt), 0)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var expected time.Duration
+	if r.backfill && r.count > 0 {
+		expected = r.sum / time.Duration(r.count)
+	}
+	histogram(r.intervals, from, r.digits).Record(red.Duration)
+	histogram(r.corrected, from, r.digits).RecordCorrected(corrected, expected)
+	r.sum += red.Duration
+	r.count++
+}
+
+// histogram returns the histogram of the interval starting at from, creating it with the
+// given precision when there is none yet.
+func histogram(intervals map[time.Duration]*Histogram, from time.Duration, digits int) *Histogram {
+	h := intervals[from]
+	if h == nil {
+		h = NewHistogram(digits)
+		intervals[from] = h
+	}
+	return h
+}
+
+// Merge adds the histograms of other to the ones of the recorder, interval by interval.
+func (r *Recorder) Merge(other *Recorder) error {
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for from, h := range other.intervals {
+		if err := histogram(r.intervals, from, r.digits).Merge(h); err != nil {
+			return err
+		}
+	}
+	for from, h := range other.corrected {
+		if err := histogram(r.corrected, from, r.digits).Merge(h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Interval returns the histogram of the interval starting at from, offset from the start
+// of the run, or nil when no request sent during it got a response.
+func (r *Recorder) Interval(from time.Duration) *Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.intervals[from]
+}
+
+// Total returns the histogram of all the intervals merged.
+func (r *Recorder) Total() *Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return merged(r.intervals, r.digits)
+}
+
+// Corrected returns the histogram of the durations corrected for coordinated omission of
+// all the intervals merged.
+func (r *Recorder) Corrected() *Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return merged(r.corrected, r.digits)
+}
+
+// merged returns a histogram of the given precision with all the given ones merged.
+func merged(intervals map[time.Duration]*Histogram, digits int) *Histogram {
+	total := NewHistogram(digits)
+	for _, h := range intervals {
+		total.Merge(h)
+	}
+	return total
+}
@@ -0,0 +1,188 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+func TestHistogram_Percentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		digits int
+	}{
+		{name: "1 digit", digits: 1},
+		{name: "3 digits", digits: 3},
+		{name: "5 digits", digits: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			h := NewHistogram(tt.digits)
+			durations := make([]time.Duration, 10000)
+			for i := range durations {
+				durations[i] = time.Duration(r.ExpFloat64() * float64(50*time.Millisecond))
+				h.Record(durations[i])
+			}
+			sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+			tolerance := math.Pow10(-tt.digits)
+			for _, p := range []float64{10, 50, 90, 99, 99.9} {
+				want := durations[int(math.Ceil(float64(len(durations))*p/100-1e-9))-1]
+				got := h.Percentile(p)
+				if err := math.Abs(float64(got-want)) / float64(want); err > tolerance {
+					t.Errorf("Percentile(%v) = %v, want %v within %v", p, got, want, tolerance)
+				}
+			}
+			if h.Min() != durations[0] || h.Max() != durations[len(durations)-1] || h.Count() != len(durations) {
+				t.Errorf("Min(), Max(), Count() = %v, %v, %v, want %v, %v, %v", h.Min(), h.Max(), h.Count(), durations[0], durations[len(durations)-1], len(durations))
+			}
+		})
+	}
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	all, a, b := NewHistogram(3), NewHistogram(3), NewHistogram(3)
+	for i := range 1000 {
+		d := time.Duration(i*i) * time.Microsecond
+		all.Record(d)
+		if i%3 == 0 {
+			a.Record(d)
+		} else {
+			b.Record(d)
+		}
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if !reflect.DeepEqual(a.counts, all.counts) || a.Count() != all.Count() || a.Mean() != all.Mean() || a.Min() != all.Min() || a.Max() != all.Max() {
+		t.Errorf("Merge() = %+v, want %+v", a, all)
+	}
+	if diff := a.StdDev() - all.StdDev(); diff < -time.Microsecond || diff > time.Microsecond {
+		t.Errorf("Merge() StdDev() = %v, want %v", a.StdDev(), all.StdDev())
+	}
+	if err := a.Merge(NewHistogram(2)); err == nil {
+		t.Errorf("Merge() of another precision error = nil")
+	}
+}
+
+func TestHistogram_RecordCorrected(t *testing.T) {
+	tests := []struct {
+		name     string
+		d        time.Duration
+		expected time.Duration
+		want     []time.Duration
+	}{
+		{name: "No expected interval", d: 1000, expected: 0, want: []time.Duration{1000}},
+		{name: "Faster than expected", d: 100, expected: 200, want: []time.Duration{100}},
+		{name: "Stalled", d: 1000, expected: 300, want: []time.Duration{1000, 700, 400}},
+		{name: "Stalled to the interval", d: 900, expected: 300, want: []time.Duration{900, 600, 300}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, want := NewHistogram(3), NewHistogram(3)
+			got.RecordCorrected(tt.d, tt.expected)
+			for _, d := range tt.want {
+				want.Record(d)
+			}
+			if !reflect.DeepEqual(got.counts, want.counts) || got.Count() != want.Count() || got.Min() != want.Min() {
+				t.Errorf("RecordCorrected() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	start := time.Now()
+	red := func(at, d time.Duration, status int) *dto.Red {
+		return &dto.Red{SentAt: start.Add(at), StatusCode: status, Duration: d}
+	}
+	w1, w2 := NewRecorder(start, time.Second, 3, false), NewRecorder(start, time.Second, 3, false)
+	w1.Record(red(0, 10*time.Millisecond, 200))
+	w1.Record(red(1500*time.Millisecond, 30*time.Millisecond, 500))
+	w2.Record(red(200*time.Millisecond, 20*time.Millisecond, 200))
+	w2.Record(red(1200*time.Millisecond, 5*time.Second, -1))
+
+	total := NewRecorder(start, time.Second, 3, false)
+	for _, w := range []*Recorder{w1, w2} {
+		if err := total.Merge(w); err != nil {
+			t.Fatalf("Merge() error = %v", err)
+		}
+	}
+	if h := total.Interval(0); h.Count() != 2 || h.Min() != 10*time.Millisecond || h.Max() != 20*time.Millisecond {
+		t.Errorf("Interval(0) = %+v", h)
+	}
+	if h := total.Interval(time.Second); h.Count() != 1 || h.Max() != 30*time.Millisecond {
+		t.Errorf("Interval(1s) = %+v", h)
+	}
+	if h := total.Total(); h.Count() != 3 || h.Mean() != 20*time.Millisecond {
+		t.Errorf("Total() = %+v", h)
+	}
+}
+
+func TestRecorder_Corrected(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name     string
+		backfill bool
+		reds     []dto.Red
+		want     []time.Duration
+	}{
+		{
+			name: "Sent on time",
+			reds: []dto.Red{
+				{IntendedAt: start, SentAt: start, Duration: 100},
+				{SentAt: start.Add(200), Duration: 300},
+			},
+			want: []time.Duration{100, 300},
+		},
+		{
+			name: "Sent late",
+			reds: []dto.Red{
+				{IntendedAt: start, SentAt: start.Add(50), Duration: 100},
+				{IntendedAt: start.Add(100), SentAt: start.Add(400), Duration: 100},
+				{IntendedAt: start.Add(500), SentAt: start.Add(400), Duration: 100},
+			},
+			want: []time.Duration{150, 400, 100},
+		},
+		{
+			name:     "Backfilled",
+			backfill: true,
+			reds: []dto.Red{
+				{IntendedAt: start, SentAt: start, Duration: 100},
+				{IntendedAt: start.Add(100), SentAt: start.Add(100), Duration: 100},
+				{IntendedAt: start.Add(200), SentAt: start.Add(200), Duration: 400},
+			},
+			want: []time.Duration{100, 100, 400, 300, 200, 100},
+		},
+		{
+			name:     "Network errors left out",
+			backfill: true,
+			reds: []dto.Red{
+				{IntendedAt: start, SentAt: start, StatusCode: -1, Duration: 1000},
+				{IntendedAt: start, SentAt: start, Duration: 100},
+			},
+			want: []time.Duration{100},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRecorder(start, time.Second, 3, tt.backfill)
+			for i := range tt.reds {
+				r.Record(&tt.reds[i])
+			}
+			want := NewHistogram(3)
+			for _, d := range tt.want {
+				want.Record(d)
+			}
+			if got := r.Corrected(); !reflect.DeepEqual(got.counts, want.counts) || got.Count() != want.Count() {
+				t.Errorf("Corrected() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
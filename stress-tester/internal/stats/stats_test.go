@@ -10,7 +10,8 @@ import (
 
 func TestCalculateRed(t *testing.T) {
 	type args struct {
-		recs []*dto.Red
+		recs  []*dto.Red
+		width time.Duration
 	}
 	tests := []struct {
 		name string
@@ -20,24 +21,122 @@ func TestCalculateRed(t *testing.T) {
 		{
 			name: "Success",
 			args: args{
-				recs: mockReds,
+				recs:  mockReds,
+				width: time.Second,
 			},
 
 			want: map[string]*dto.ResultRed{
-				"0000": {
+				"0s": {
+					Width:                        time.Second,
+					NumRequestPerSecond:          10,
+					NumRequestWithErrorPerSecond: 0,
+					NumNetworkErrorPerSecond:     0,
+					AverageDuration:              time.Duration(10 * time.Second),
+					MaxDuration:                  time.Duration(time.Second),
+					MinDuration:                  time.Duration(time.Second),
+					Percentiles:                  percentiles(500, 1000, 600, 200, 500, 500, 500, 500, 1000, 1000),
+				},
+				"1s": {
+					From:                         time.Second,
+					Width:                        time.Second,
+					NumRequestPerSecond:          17,
+					NumRequestWithErrorPerSecond: 10,
+					NumNetworkErrorPerSecond:     0,
+					AverageDuration:              time.Duration(17 * time.Second),
+					MaxDuration:                  time.Duration(time.Second),
+					MinDuration:                  time.Duration(time.Second),
+					Percentiles:                  percentiles(100, 500, 170, 153, 100, 100, 100, 100, 500, 500),
+				},
+				"2s": {
+					From:                         2 * time.Second,
+					Width:                        time.Second,
+					NumRequestPerSecond:          3,
+					NumRequestWithErrorPerSecond: 3,
+					NumNetworkErrorPerSecond:     0,
+					AverageDuration:              time.Duration(3 * time.Second),
+					MaxDuration:                  time.Duration(time.Second),
+					MinDuration:                  time.Duration(time.Second),
+					Percentiles:                  percentiles(50, 50, 50, 0, 50, 50, 50, 50, 50, 50),
+				},
+			},
+		},
+		{
+			name: "Single interval",
+			args: args{
+				recs:  mockReds,
+				width: time.Minute,
+			},
+
+			want: map[string]*dto.ResultRed{
+				"0s": {
+					Width:                        3 * time.Second,
 					NumRequestPerSecond:          30,
 					NumRequestWithErrorPerSecond: 13,
 					NumNetworkErrorPerSecond:     0,
 					AverageDuration:              time.Duration(30 * time.Second),
 					MaxDuration:                  time.Duration(time.Second),
 					MinDuration:                  time.Duration(time.Second),
+					Percentiles:                  percentiles(50, 1000, 301, 269, 50, 100, 100, 500, 500, 1000),
+				},
+			},
+		},
+		{
+			name: "Network errors",
+			args: args{
+				recs: []*dto.Red{
+					{SentAt: now, ReceivedAt: now.Add(5 * time.Second), StatusCode: -1, FailedCheck: "status 200", Duration: 5 * time.Second},
+					{SentAt: now, ReceivedAt: now.Add(2 * time.Second), StatusCode: 200, Duration: 2 * time.Second},
+					{SentAt: now, ReceivedAt: now.Add(3 * time.Second), StatusCode: 200, Duration: 3 * time.Second},
+				},
+				width: time.Second,
+			},
+
+			want: map[string]*dto.ResultRed{
+				"0s": {
+					Width:                    time.Second,
+					NumRequestPerSecond:      3,
+					NumNetworkErrorPerSecond: 1,
+					AverageDuration:          5 * time.Second,
+					MaxDuration:              3 * time.Second,
+					MinDuration:              2 * time.Second,
+					Percentiles:              percentiles(2*time.Second, 3*time.Second, 2500*time.Millisecond, 500*time.Millisecond, 2*time.Second, 2*time.Second, 2*time.Second, 3*time.Second, 3*time.Second, 3*time.Second),
+				},
+			},
+		},
+		{
+			name: "Partial last interval",
+			args: args{
+				recs: []*dto.Red{
+					{SentAt: now, ReceivedAt: now.Add(time.Second), StatusCode: 200, Duration: time.Second},
+					{SentAt: now.Add(time.Second), ReceivedAt: now.Add(1500 * time.Millisecond), StatusCode: 200, Duration: 500 * time.Millisecond},
+				},
+				width: time.Second,
+			},
+
+			want: map[string]*dto.ResultRed{
+				"0s": {
+					Width:               time.Second,
+					NumRequestPerSecond: 1,
+					AverageDuration:     time.Second,
+					MaxDuration:         time.Second,
+					MinDuration:         time.Second,
+					Percentiles:         percentiles(time.Second, time.Second, time.Second, 0, time.Second, time.Second, time.Second, time.Second, time.Second, time.Second),
+				},
+				"1s": {
+					From:                time.Second,
+					Width:               500 * time.Millisecond,
+					NumRequestPerSecond: 1,
+					AverageDuration:     500 * time.Millisecond,
+					MaxDuration:         500 * time.Millisecond,
+					MinDuration:         500 * time.Millisecond,
+					Percentiles:         percentiles(500*time.Millisecond, 500*time.Millisecond, 500*time.Millisecond, 0, 500*time.Millisecond, 500*time.Millisecond, 500*time.Millisecond, 500*time.Millisecond, 500*time.Millisecond, 500*time.Millisecond),
 				},
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := CalculateRed(tt.args.recs); !reflect.DeepEqual(got, tt.want) {
+			if got := CalculateRed(tt.args.recs, now, tt.args.width, DefaultDigits, testPercentiles); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("CalculateRed() = %v, want %v", got, tt.want)
 			}
 		})
@@ -79,6 +178,56 @@ func TestCalculateErrors(t *testing.T) {
 	}
 }
 
+func TestAggregator_NetErrors(t *testing.T) {
+	recs := []*dto.Red{
+		{StatusCode: 200},
+		{StatusCode: -1, NetError: dto.NetErrorTimeout, ErrorMessage: "first timeout"},
+		{StatusCode: -1, NetError: dto.NetErrorTimeout, ErrorMessage: "second timeout"},
+		{StatusCode: -1, NetError: dto.NetErrorConnectionRefused, ErrorMessage: "connection refused"},
+		{StatusCode: -1},
+	}
+	want := map[dto.NetError]*dto.ResultNetError{
+		dto.NetErrorTimeout:           {Category: dto.NetErrorTimeout, Requests: 2, Sample: "first timeout"},
+		dto.NetErrorConnectionRefused: {Category: dto.NetErrorConnectionRefused, Requests: 1, Sample: "connection refused"},
+		dto.NetErrorOther:             {Category: dto.NetErrorOther, Requests: 1},
+	}
+	if got := Aggregate(recs, Settings{}).NetErrors(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Aggregator.NetErrors() = %v, want %v", got, want)
+	}
+}
+
+func TestAggregator_Sizes(t *testing.T) {
+	tests := []struct {
+		name string
+		recs []*dto.Red
+		want dto.Sizes
+	}{
+		{
+			name: "Success",
+			recs: []*dto.Red{
+				{StatusCode: 200, BytesReceived: 400},
+				{StatusCode: 200, BytesReceived: 100},
+				{StatusCode: 500, BytesReceived: 300},
+				{StatusCode: 200, BytesReceived: 200},
+				{StatusCode: -1, BytesSent: 50},
+			},
+			want: dto.Sizes{Values: []dto.Size{{Percentile: 50, Bytes: 200}, {Percentile: 99, Bytes: 400}}, Min: 100, Max: 400, Mean: 250},
+		},
+		{
+			name: "No responses",
+			recs: []*dto.Red{{StatusCode: -1}},
+			want: dto.Sizes{Values: []dto.Size{{Percentile: 50}, {Percentile: 99}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Aggregate(tt.recs, Settings{Digits: 3, Percentiles: []float64{50, 99}}).Sizes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Aggregator.Sizes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestCalculatePercentile(t *testing.T) {
 	type args struct {
 		recs []*dto.Red
@@ -93,25 +242,152 @@ func TestCalculatePercentile(t *testing.T) {
 			args: args{
 				recs: mockReds,
 			},
-			want: dto.Percentiles{
-				P10: time.Duration(100 * time.Nanosecond),
-				P25: time.Duration(100 * time.Nanosecond),
-				P50: time.Duration(100 * time.Nanosecond),
-				P75: time.Duration(500 * time.Nanosecond),
-				P90: time.Duration(500 * time.Nanosecond),
-				P99: time.Duration(1 * time.Microsecond),
+			want: percentiles(50, 1000, 301, 269, 50, 100, 100, 500, 500, 1000),
+		},
+		{
+			name: "Small sample",
+			args: args{
+				recs: []*dto.Red{
+					{StatusCode: 200, Duration: 400},
+					{StatusCode: 200, Duration: 100},
+					{StatusCode: 200, Duration: 300},
+					{StatusCode: 200, Duration: 200},
+				},
 			},
+			want: percentiles(100, 400, 250, 111, 100, 100, 200, 300, 400, 400),
+		},
+		{
+			name: "No responses",
+			args: args{
+				recs: []*dto.Red{{StatusCode: -1, Duration: time.Second}},
+			},
+			want: percentiles(0, 0, 0, 0, 0, 0, 0, 0, 0, 0),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := CalculatePercentile(tt.args.recs); !reflect.DeepEqual(got, tt.want) {
+			if got := CalculatePercentile(tt.args.recs, DefaultDigits, testPercentiles); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("CalculatePercentile() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestAggregator_Phases(t *testing.T) {
+	recs := []*dto.Red{
+		{StatusCode: 200, Phases: dto.Phases{DNS: 100, Connect: 200, TLS: 300, TTFB: 1000, Transfer: 10}},
+		{StatusCode: 200, Phases: dto.Phases{TTFB: 2000, Transfer: 20, Reused: true}},
+		{StatusCode: 500, Phases: dto.Phases{TTFB: 3000, Transfer: 30, Reused: true}},
+		{StatusCode: -1, Phases: dto.Phases{DNS: 5000, Connect: 5000}},
+	}
+	got := Aggregate(recs, Settings{Digits: 3, Percentiles: []float64{50}}).Phases()
+	if got.Requests != 3 || got.Reused != 2 {
+		t.Errorf("Aggregator.Phases() Requests, Reused = %v, %v, want 3, 2", got.Requests, got.Reused)
+	}
+	want := []struct {
+		phase    string
+		requests int
+		p50      time.Duration
+		max      time.Duration
+	}{
+		{"DNS", 1, 100, 100},
+		{"Connect", 1, 200, 200},
+		{"TLS", 1, 300, 300},
+		{"TTFB", 3, 2000, 3000},
+		{"Transfer", 3, 20, 30},
+	}
+	if len(got.Phases) != len(want) {
+		t.Fatalf("Aggregator.Phases() = %+v, want %d phases", got.Phases, len(want))
+	}
+	for i, w := range want {
+		phase := got.Phases[i]
+		if phase.Phase != w.phase || phase.Requests != w.requests || phase.Percentiles.Values[0].Duration != w.p50 || phase.Percentiles.Max != w.max {
+			t.Errorf("Aggregator.Phases() phase %d = %+v, want %+v", i, phase, w)
+		}
+	}
+}
+
+func TestJourneyAggregator(t *testing.T) {
+	runs := []*dto.JourneyRed{
+		{Name: "checkout", Duration: 40 * time.Millisecond},
+		{Name: "checkout", Duration: 10 * time.Millisecond},
+		{Name: "checkout", Duration: 20 * time.Millisecond, Failed: true},
+		{Name: "checkout", Duration: 30 * time.Millisecond},
+	}
+	a := NewJourneyAggregator(DefaultDigits, testPercentiles)
+	for _, r := range runs {
+		a.Add(r)
+	}
+	got := a.Result()
+	want := dto.ResultJourney{
+		Name:            "checkout",
+		Runs:            4,
+		Failed:          1,
+		AverageDuration: 25 * time.Millisecond,
+		MinDuration:     10 * time.Millisecond,
+		MaxDuration:     40 * time.Millisecond,
+	}
+	got.Percentiles = dto.Percentiles{}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JourneyAggregator.Result() = %v, want %v", got, want)
+	}
+	want = a.Result()
+	if p := want.Percentiles.Values; p[0].Duration != 10*time.Millisecond || p[5].Duration != 40*time.Millisecond {
+		t.Errorf("JourneyAggregator.Result() percentiles = %v", p)
+	}
+}
+
+func TestEvaluateThresholds(t *testing.T) {
+	parse := func(metric, op string, value float64, rate bool) dto.Threshold {
+		return dto.Threshold{Text: metric + op, Metric: metric, Op: op, Value: value, Rate: rate}
+	}
+	p99 := parse("p99", "<", float64(time.Microsecond), false)
+	p99.Percentile = 99
+	p50 := parse("p50", "<=", 100, false)
+	p50.Percentile = 50
+	thresholds := []dto.Threshold{
+		p99,
+		p50,
+		parse("max", "<", 1000, false),
+		parse("error_rate", "<", 0.5, true),
+		parse("rps", ">", 10, false),
+		parse("requests", ">=", 30, false),
+		parse("errors", "<", 10, false),
+		parse("status_500", "<", 0.1, true),
+		parse("status_5xx", "<=", 13, false),
+		parse("status_2xx", ">", 17, false),
+	}
+	want := []dto.ResultThreshold{
+		{Threshold: "p99<", Actual: "1µs", Passed: false},
+		{Threshold: "p50<=", Actual: "100ns", Passed: true},
+		{Threshold: "max<", Actual: "1µs", Passed: false},
+		{Threshold: "error_rate<", Actual: "43.33%", Passed: true},
+		{Threshold: "rps>", Actual: "15.00", Passed: true},
+		{Threshold: "requests>=", Actual: "30", Passed: true},
+		{Threshold: "errors<", Actual: "13", Passed: false},
+		{Threshold: "status_500<", Actual: "43.33%", Passed: false},
+		{Threshold: "status_5xx<=", Actual: "13", Passed: true},
+		{Threshold: "status_2xx>", Actual: "17", Passed: false},
+	}
+	got := EvaluateThresholds(mockReds, 2*time.Second, thresholds, DefaultDigits)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EvaluateThresholds() = %v, want %v", got, want)
+	}
+}
+
+// testPercentiles are the percentiles the tests ask for.
+var testPercentiles = []float64{10, 25, 50, 75, 90, 99}
+
+// percentiles returns the dto.Percentiles with the given min, max, mean, standard
+// deviation and durations at each of testPercentiles.
+func percentiles(min, max, mean, stddev time.Duration, values ...time.Duration) dto.Percentiles {
+	p := dto.Percentiles{Min: min, Max: max, Mean: mean, StdDev: stddev}
+	for i, v := range values {
+		p.Values = append(p.Values, dto.Percentile{Percentile: testPercentiles[i], Duration: v})
+	}
+	return p
+}
+
 var now = time.Now()
 var now2 = now.Add(1 * time.Second)
 var now3 = now.Add(2 * time.Second)
@@ -238,94 +514,107 @@ var mockReds = []*dto.Red{
 		Duration:   time.Duration(100),
 	},
 	{
-		Target:     "test",
-		SentAt:     now2,
-		ReceivedAt: now3,
-		StatusCode: 500,
-		Duration:   time.Duration(100),
+		Target:      "test",
+		SentAt:      now2,
+		ReceivedAt:  now3,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(100),
 	},
 	{
-		Target:     "test",
-		SentAt:     now2,
-		ReceivedAt: now3,
-		StatusCode: 500,
-		Duration:   time.Duration(100),
+		Target:      "test",
+		SentAt:      now2,
+		ReceivedAt:  now3,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(100),
 	},
 	{
-		Target:     "test",
-		SentAt:     now2,
-		ReceivedAt: now3,
-		StatusCode: 500,
-		Duration:   time.Duration(100),
+		Target:      "test",
+		SentAt:      now2,
+		ReceivedAt:  now3,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(100),
 	},
 	{
-		Target:     "test",
-		SentAt:     now2,
-		ReceivedAt: now3,
-		StatusCode: 500,
-		Duration:   time.Duration(100),
+		Target:      "test",
+		SentAt:      now2,
+		ReceivedAt:  now3,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(100),
 	},
 	{
-		Target:     "test",
-		SentAt:     now2,
-		ReceivedAt: now3,
-		StatusCode: 500,
-		Duration:   time.Duration(100),
+		Target:      "test",
+		SentAt:      now2,
+		ReceivedAt:  now3,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(100),
 	},
 	{
-		Target:     "test",
-		SentAt:     now2,
-		ReceivedAt: now3,
-		StatusCode: 500,
-		Duration:   time.Duration(100),
+		Target:      "test",
+		SentAt:      now2,
+		ReceivedAt:  now3,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(100),
 	},
 	{
-		Target:     "test",
-		SentAt:     now2,
-		ReceivedAt: now3,
-		StatusCode: 500,
-		Duration:   time.Duration(100),
+		Target:      "test",
+		SentAt:      now2,
+		ReceivedAt:  now3,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(100),
 	},
 	{
-		Target:     "test",
-		SentAt:     now2,
-		ReceivedAt: now3,
-		StatusCode: 500,
-		Duration:   time.Duration(100),
+		Target:      "test",
+		SentAt:      now2,
+		ReceivedAt:  now3,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(100),
 	},
 	{
-		Target:     "test",
-		SentAt:     now2,
-		ReceivedAt: now3,
-		StatusCode: 500,
-		Duration:   time.Duration(100),
+		Target:      "test",
+		SentAt:      now2,
+		ReceivedAt:  now3,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(100),
 	},
 	{
-		Target:     "test",
-		SentAt:     now2,
-		ReceivedAt: now3,
-		StatusCode: 500,
-		Duration:   time.Duration(100),
+		Target:      "test",
+		SentAt:      now2,
+		ReceivedAt:  now3,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(100),
 	},
 	{
-		Target:     "test",
-		SentAt:     now3,
-		ReceivedAt: now4,
-		StatusCode: 500,
-		Duration:   time.Duration(50),
+		Target:      "test",
+		SentAt:      now3,
+		ReceivedAt:  now4,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(50),
 	},
 	{
-		Target:     "test",
-		SentAt:     now3,
-		ReceivedAt: now4,
-		StatusCode: 500,
-		Duration:   time.Duration(50),
+		Target:      "test",
+		SentAt:      now3,
+		ReceivedAt:  now4,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(50),
 	},
 	{
-		Target:     "test",
-		SentAt:     now3,
-		ReceivedAt: now4,
-		StatusCode: 500,
-		Duration:   time.Duration(50),
+		Target:      "test",
+		SentAt:      now3,
+		ReceivedAt:  now4,
+		StatusCode:  500,
+		FailedCheck: "status 200",
+		Duration:    time.Duration(50),
 	},
 }
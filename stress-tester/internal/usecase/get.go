@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"stress-tester/internal/db"
@@ -10,92 +11,328 @@ import (
 	"stress-tester/internal/pool"
 	"stress-tester/internal/report"
 	"stress-tester/internal/stats"
+	"strings"
 	"sync"
 	"time"
 )
 
-type httpGet struct {
-	Client        *http.Client
-	Target        string
-	ReturnChannel chan *dto.Red
-	NumRequests   int
-}
+// ErrInterrupted is the cause of the cancellation of the context given to RoutineGet when
+// the user interrupted the run.
+var ErrInterrupted = errors.New("interrupted")
 
-// newHttpGet creates an httpGet object with the given http client, target, number of requests and
-// return channel.
-func newHttpGet(client *http.Client, target string, numRequests int, rec chan *dto.Red) *httpGet {
-	return &httpGet{
-		Client:        client,
-		Target:        target,
-		ReturnChannel: rec,
-		NumRequests:   numRequests,
+// drain waits for the workers to finish. Once the run is stopped early it waits at most
+// grace, when grace is greater than zero, and tells whether all the workers finished.
+func drain(run context.Context, wg *sync.WaitGroup, grace time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-run.Done():
+	}
+	if grace <= 0 {
+		<-done
+		return true
+	}
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
 	}
 }
-// executeGet runs the http gets in a loop, stopping when the context is canceled,
-// and sends the results of each get down the channel.
-func (h *httpGet) executeGet(ctx context.Context, wg *sync.WaitGroup) {
-	for range h.NumRequests {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			go func(client *http.Client, target string, rec chan *dto.Red, wg *sync.WaitGroup) {
-				r := &entity.Red{
-					Target: target,
-				}
-				r.Get(client)
-				dto := &dto.Red{Target: r.Target, SentAt: r.SentAt, ReceivedAt: r.ReceivedAt, StatusCode: r.StatusCode, Duration: r.ReceivedAt.Sub(r.SentAt)}
-				rec <- dto
-				wg.Done()
-			}(h.Client, h.Target, h.ReturnChannel, wg)
-		}
+
+// ErrThresholds is returned by RoutineGet when one of the thresholds of the load did not
+// pass.
+var ErrThresholds = errors.New("thresholds breached")
+
+// send sends a single request to the endpoint, verifies its response with the checks and
+// records its result, tagged with the given stage and the time it was meant to be sent.
+// Canceling the context cancels the request.
+func send(ctx context.Context, client *http.Client, endpoint dto.Endpoint, checks entity.Checks, stage int, intended time.Time, record func(*dto.Red)) {
+	r := &entity.Red{
+		Method:  endpoint.Method,
+		Target:  endpoint.URL,
+		Header:  endpoint.Header,
+		Payload: endpoint.Body,
+		Capture: checks.Capture(),
 	}
+	r.Do(ctx, client)
+	checks.Verify(r)
+	record(newRed(endpoint.Name, intended, stage, r))
 }
 
-// RoutineGet runs a number of GET requests against a target url and stores the responses in
-// a database. It will run the given number of requests, but will do so in batches of
-// concurrency. It will cancel any remaining work when all requests have been completed.
-// It will then generate a report on the stored data and print it to the console.
-func RoutineGet(target string, requests int, concurrency int) {
-	start := time.Now()
+// newRed returns the record of the request sent to the endpoint with the given name, meant
+// to be sent at intended during the given stage.
+func newRed(name string, intended time.Time, stage int, r *entity.Red) *dto.Red {
+	return &dto.Red{Name: name, Target: r.Target, IntendedAt: intended, SentAt: r.SentAt, ReceivedAt: r.ReceivedAt, StatusCode: r.StatusCode, Duration: r.ReceivedAt.Sub(r.SentAt), Stage: stage, FailedCheck: r.FailedCheck, Phases: r.Phases, NetError: r.NetError, ErrorMessage: r.ErrorMessage, BytesSent: r.BytesSent, BytesReceived: r.BytesReceived}
+}
 
-	rounds := int(float64(requests) / float64(concurrency))
-	extra := requests - concurrency*rounds
+// RoutineGet runs the load, see dto.Load, storing the responses in its store, and writes
+// the report in each of its outputs. It returns the reason the run stopped early, when it
+// did, or ErrThresholds when a threshold did not pass.
+func RoutineGet(parent context.Context, load dto.Load) error {
+	start := time.Now()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	rec := make(chan *dto.Red)
+	traffic, err := newTraffic(load)
+	if err != nil {
+		return err
+	}
 
-	database := db.NewDB(pool.GetDb(), rec)
-	defer database.Close()
+	settings := stats.Settings{Start: start, Width: load.Interval, Digits: load.Precision, Percentiles: load.Percentiles}
+	store, err := openStore(load.Store, settings)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	var meta *dto.Run
+	if log, ok := store.(db.RunLog); ok {
+		r, err := newRun(load, start)
+		if err != nil {
+			return err
+		}
+		if err := log.StartRun(r); err != nil {
+			return err
+		}
+		meta = &r
+	}
 
-	go database.Store(ctx)
+	ingest := db.NewIngest(store, db.DefaultBuffer, db.DefaultBatch, db.DefaultFlush)
 
-	wg := sync.WaitGroup{}
+	numWorkers := load.Concurrency
+	profile := &entity.LoadProfile{Stages: load.Stages}
+	var stages *stageState
+	switch {
+	case load.Rate > 0:
+		numWorkers = load.MaxInFlight
+	case len(load.Stages) > 0:
+		numWorkers = profile.MaxConcurrency()
+		stages = &stageState{over: make(chan struct{})}
+	}
 
-	for i := range rounds {
-		fmt.Println("Round ", i, "Running ", concurrency, " requests for endpoint ", target)
-		hg := newHttpGet(pool.GetHttpClient(), target, concurrency, rec)
-		wg.Add(concurrency)
-		hg.executeGet(ctx, &wg)
+	target := describeTraffic(load)
+	client := pool.GetHttpClient()
+	client.Timeout = load.Timeout
+	run, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	defer context.AfterFunc(parent, func() { stop(context.Cause(parent)) })()
+	monitor := newMonitor(load.Aborts, load.Precision)
+	if monitor != nil {
+		go monitor.watch(run, start, stop)
+	}
+	jobs := make(chan job)
+	workers := make([]*worker, numWorkers)
+	wg := sync.WaitGroup{}
+	for i := range workers {
+		workers[i] = newWorker(i, client, traffic, stages, ingest, stop, monitor, stats.NewRecorder(start, load.Interval, load.Precision, load.Rate == 0))
+		wg.Add(1)
+		go workers[i].run(run, ctx, jobs, &wg)
 	}
 
-	if extra > 0 {
-		fmt.Println("Round ", rounds, "Running ", extra, " requests for endpoint ", target)
-		hg := newHttpGet(pool.GetHttpClient(), target, extra, rec)
-		wg.Add(extra)
-		hg.executeGet(ctx, &wg)
+	var schedule *dto.ResultSchedule
+	switch {
+	case load.Rate > 0:
+		fmt.Println("Running ", load.Rate, " requests per second for endpoint ", target, " with at most ", load.MaxInFlight, " in flight")
+		result := dispatchAtRate(run, jobs, load)
+		schedule = &result
+	case stages != nil:
+		fmt.Println("Running ", len(load.Stages), " stages with up to ", numWorkers, " workers for endpoint ", target, " during ", profile.Duration())
+		followed := make(chan struct{})
+		go func() {
+			followProfile(run, profile, stages)
+			close(followed)
+		}()
+		dispatchForDuration(run, jobs, profile.Duration())
+		close(stages.over)
+		// No stage line is printed once the run is over.
+		<-followed
+	case load.Duration > 0:
+		fmt.Println("Running ", numWorkers, " workers for endpoint ", target, " during ", load.Duration)
+		dispatchForDuration(run, jobs, load.Duration)
+	default:
+		fmt.Println("Running ", load.Requests, " requests with ", numWorkers, " workers for endpoint ", target)
+		dispatchRequests(run, jobs, load.Requests)
 	}
 
-	wg.Wait()
-	time.Sleep(time.Millisecond)
+	drained := drain(run, &wg, load.Grace)
+	stopped := context.Cause(run)
+	// Cancels the requests still in flight when the grace ran out, so their workers return.
 	cancel()
+	wg.Wait()
+	ingested := ingest.Close()
+	if meta != nil {
+		meta.FinishedAt = time.Now()
+		if err := store.(db.RunLog).FinishRun(meta.ID, meta.FinishedAt); err != nil {
+			return err
+		}
+	}
+
+	summary := dto.Summary{
+		Target:   target,
+		Elapsed:  time.Since(start),
+		Workers:  make([]dto.ResultWorker, len(workers)),
+		Schedule: schedule,
+		Ingest:   &ingested,
+	}
+	if meta != nil && meta.Path != "" {
+		summary.Run = meta
+	}
+	if stopped != nil {
+		summary.Stopped = stopped.Error()
+		summary.Interrupted = errors.Is(stopped, ErrInterrupted)
+	}
+	if !drained {
+		summary.Stopped += fmt.Sprint(", gave up waiting for the requests in flight after ", load.Grace)
+	}
+	recorder := stats.NewRecorder(start, load.Interval, load.Precision, false)
+	for i, w := range workers {
+		summary.Workers[i] = w.result()
+		summary.Requests += w.Requests
+		summary.Canceled += w.Canceled
+		if err := recorder.Merge(w.Recorder); err != nil {
+			return err
+		}
+	}
+	summary.Percentiles = recorder.Total().Percentiles(load.Percentiles)
+	summary.Corrected = recorder.Corrected().Percentiles(load.Percentiles)
+	for i, stage := range load.Stages {
+		result := dto.ResultStage{Stage: stage}
+		if i > 0 {
+			result.From = load.Stages[i-1].Target
+		}
+		a, err := store.Aggregate(db.Filter{Stage: i, ByStage: true})
+		if err != nil {
+			return err
+		}
+		if a.Count() > 0 {
+			result.Red = a.Red()
+			result.Percentiles = a.Percentiles()
+		}
+		summary.Stages = append(summary.Stages, result)
+	}
+	if len(load.Endpoints) > 1 {
+		for i, endpoint := range load.Endpoints {
+			result, err := endpointResult(store, db.Filter{}, endpoint, traffic.mix.Share(i))
+			if err != nil {
+				return err
+			}
+			summary.Endpoints = append(summary.Endpoints, result)
+		}
+	}
+	for i, journey := range load.Journeys {
+		result := dto.ResultJourney{Name: journey.Name, Share: traffic.mix.Share(i)}
+		a, err := store.AggregateJourney(journey.Name)
+		if err != nil {
+			return err
+		}
+		if a.Count() > 0 {
+			result = a.Result()
+			result.Share = traffic.mix.Share(i)
+		}
+		summary.Journeys = append(summary.Journeys, result)
+		for _, step := range journey.Steps {
+			result, err := endpointResult(store, db.Filter{}, step, 0)
+			if err != nil {
+				return err
+			}
+			summary.Endpoints = append(summary.Endpoints, result)
+		}
+	}
+	summary.Checks = traffic.results()
+	all, err := store.Aggregate(db.Filter{})
+	if err != nil {
+		return err
+	}
+	if all.Count() > 0 {
+		summary.Red = all.Red()
+		summary.Errors = all.Errors()
+		summary.NetErrors = all.NetErrors()
+		summary.Phases = all.Phases()
+		summary.Sizes = all.Sizes()
+	}
+	summary.Thresholds = all.Thresholds(summary.Elapsed, load.Thresholds)
+	if err := report.Write(summary, settings, load.Outputs); err != nil {
+		return err
+	}
+	if stopped != nil {
+		if summary.Interrupted {
+			return stopped
+		}
+		return fmt.Errorf("run stopped early: %w", stopped)
+	}
+	for _, t := range summary.Thresholds {
+		if !t.Passed {
+			return ErrThresholds
+		}
+	}
+	return nil
+}
+
+// endpointResult returns the results of the requests selected by the filter sent to the
+// endpoint, or to the step of a journey, with the given share of the traffic, as
+// summarized by the store.
+func endpointResult(store db.ResultStore, filter db.Filter, endpoint dto.Endpoint, share float64) (dto.ResultEndpoint, error) {
+	result := dto.ResultEndpoint{Endpoint: endpoint, Share: share}
+	filter.Name = endpoint.Name
+	a, err := store.Aggregate(filter)
+	if err != nil {
+		return result, err
+	}
+	if a.Count() > 0 {
+		result.Red = a.Red()
+		result.Errors = a.Errors()
+		result.NetErrors = a.NetErrors()
+		result.Percentiles = a.Percentiles()
+		result.Sizes = a.Sizes()
+	}
+	return result, nil
+}
 
-	fmt.Println("Finished ", requests, " requests for endpoint ", target, " in ", time.Since(start))
-	report.ReportRed(stats.CalculateRed(database.GetAllReds()))
-	report.ReportError(stats.CalculateErrors(database.GetAllReds()))
-	report.ReportPercentiles(stats.CalculatePercentile(database.GetAllReds()))
-	database.Close()
-	time.Sleep(time.Second)
+// openStore opens the store the records of a run are kept in, summarizing them with the
+// given settings, see dto.Store. A sqlite store with a path opens, or creates, the
+// results database in that file.
+func openStore(store dto.Store, settings stats.Settings) (db.ResultStore, error) {
+	switch store.Kind {
+	case "memory":
+		return db.NewMemory(settings), nil
+	case "file":
+		return db.NewFile(store.Path, settings)
+	}
+	if store.Path == "" {
+		return db.NewDB(pool.GetDb(), nil).WithSettings(settings), nil
+	}
+	database, err := pool.OpenDb(store.Path)
+	if err != nil {
+		return nil, fmt.Errorf("store %s: %w", store.Path, err)
+	}
+	return db.NewDB(database, nil).WithSettings(settings), nil
+}
+
+// describeTraffic returns the names of the endpoints, or of the journeys, of the load,
+// separated by commas, to be shown in the report. A single endpoint is described by its
+// url.
+func describeTraffic(load dto.Load) string {
+	if len(load.Journeys) > 0 {
+		names := make([]string, len(load.Journeys))
+		for i, j := range load.Journeys {
+			names[i] = "journey " + j.Name
+		}
+		return strings.Join(names, ", ")
+	}
+	if len(load.Endpoints) == 1 {
+		return load.Endpoints[0].URL
+	}
+	names := make([]string, len(load.Endpoints))
+	for i, e := range load.Endpoints {
+		names[i] = e.Name
+	}
+	return strings.Join(names, ", ")
 }
@@ -0,0 +1,260 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"stress-tester/internal/db"
+	"stress-tester/internal/dto"
+	"stress-tester/internal/stats"
+)
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name    string
+		work    time.Duration
+		stop    bool
+		grace   time.Duration
+		drained bool
+	}{
+		{name: "Finished", work: 0, grace: time.Millisecond, drained: true},
+		{name: "Finished within the grace", work: 10 * time.Millisecond, stop: true, grace: time.Second, drained: true},
+		{name: "Grace ran out", work: time.Second, stop: true, grace: 10 * time.Millisecond, drained: false},
+		{name: "No grace", work: 20 * time.Millisecond, stop: true, grace: 0, drained: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run, stop := context.WithCancelCause(context.Background())
+			defer stop(nil)
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				time.Sleep(tt.work)
+			}()
+			if tt.stop {
+				stop(ErrInterrupted)
+			}
+			if got := drain(run, &wg, tt.grace); got != tt.drained {
+				t.Errorf("drain() = %v, want %v", got, tt.drained)
+			}
+		})
+	}
+}
+
+// fakeStore is a db.ResultStore aggregating the records it was created with, or failing
+// with err.
+type fakeStore struct {
+	db.ResultStore
+	reds []*dto.Red
+	err  error
+}
+
+func (f fakeStore) Aggregate(filter db.Filter) (*stats.Aggregator, error) {
+	var reds []*dto.Red
+	for _, r := range f.reds {
+		if filter.Match(r) {
+			reds = append(reds, r)
+		}
+	}
+	return stats.Aggregate(reds, stats.Settings{Digits: 3, Percentiles: []float64{50}}), f.err
+}
+
+func TestEndpointResult(t *testing.T) {
+	endpoint := dto.Endpoint{Name: "products"}
+	reds := []*dto.Red{
+		{Name: "products", StatusCode: 200, Duration: 100},
+		{Name: "products", StatusCode: 500, Duration: 300},
+		{Name: "cart", StatusCode: 200, Duration: 200},
+	}
+	tests := []struct {
+		name     string
+		store    fakeStore
+		requests int
+		wantErr  bool
+	}{
+		{name: "Success", store: fakeStore{reds: reds}, requests: 2},
+		{name: "No requests", store: fakeStore{reds: reds[2:]}, requests: 0},
+		{name: "Store error", store: fakeStore{err: errors.New("closed")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := endpointResult(tt.store, db.Filter{}, endpoint, 0.5)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("endpointResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			requests := 0
+			for _, r := range got.Red {
+				requests += r.NumRequestPerSecond
+			}
+			if requests != tt.requests || got.Share != 0.5 || got.Endpoint.Name != "products" {
+				t.Errorf("endpointResult() = %+v, want %d requests", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestRoutineGet_Interrupted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The target never answers.
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	endpoints := []dto.Endpoint{{Name: "GET " + server.URL, Weight: 1, Method: "GET", URL: server.URL}}
+	tests := []struct {
+		name     string
+		load     dto.Load
+		canceled int
+	}{
+		{
+			name: "No request sent",
+			load: dto.Load{Endpoints: endpoints, Concurrency: 1, Stages: []dto.Stage{{Duration: time.Minute, Target: 0}, {Duration: time.Second, Target: 1}}},
+		},
+		{
+			name:     "No response",
+			load:     dto.Load{Endpoints: endpoints, Requests: 10, Concurrency: 2, Grace: 50 * time.Millisecond},
+			canceled: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.load.Interval, tt.load.Precision, tt.load.Percentiles = time.Second, 3, []float64{50, 99}
+			tt.load.Store = dto.Store{Kind: "memory"}
+			tt.load.Outputs = []dto.Output{{Format: "text", Path: filepath.Join(dir, "report.txt")}, {Format: "json", Path: filepath.Join(dir, "report.json")}}
+			ctx, interrupt := context.WithCancelCause(context.Background())
+			time.AfterFunc(50*time.Millisecond, func() { interrupt(ErrInterrupted) })
+
+			if err := RoutineGet(ctx, tt.load); !errors.Is(err, ErrInterrupted) {
+				t.Fatalf("RoutineGet() error = %v, want %v", err, ErrInterrupted)
+			}
+			summary := readSummary(t, filepath.Join(dir, "report.json"))
+			// The requests the run canceled itself are not failures of the target.
+			if !summary.Interrupted || summary.Requests != 0 || summary.Canceled != tt.canceled || len(summary.NetErrors) > 0 {
+				t.Errorf("RoutineGet() summary = %+v, want no requests and %d canceled", summary, tt.canceled)
+			}
+		})
+	}
+}
+
+// readSummary reads the summary of a run from its JSON report.
+func readSummary(t *testing.T, path string) dto.Summary {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var summary dto.Summary
+	if err := json.Unmarshal(b, &summary); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	return summary
+}
+
+// testLoad returns a load of the endpoints kept in memory, reported as JSON in dir.
+func testLoad(endpoints []dto.Endpoint, dir string) dto.Load {
+	return dto.Load{
+		Endpoints:   endpoints,
+		Interval:    time.Second,
+		Precision:   3,
+		Percentiles: []float64{50, 99},
+		Store:       dto.Store{Kind: "memory"},
+		Outputs:     []dto.Output{{Format: "json", Path: filepath.Join(dir, "report.json")}},
+	}
+}
+
+func TestRoutineGet_Duration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer server.Close()
+	endpoints := []dto.Endpoint{{Name: "GET " + server.URL, Weight: 1, Method: "GET", URL: server.URL}}
+	tests := []struct {
+		name        string
+		duration    time.Duration
+		concurrency int
+	}{
+		{name: "One worker", duration: 100 * time.Millisecond, concurrency: 1},
+		{name: "Several workers", duration: 200 * time.Millisecond, concurrency: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			load := testLoad(endpoints, dir)
+			load.Duration, load.Concurrency = tt.duration, tt.concurrency
+			if err := RoutineGet(context.Background(), load); err != nil {
+				t.Fatalf("RoutineGet() error = %v", err)
+			}
+			summary := readSummary(t, filepath.Join(dir, "report.json"))
+			if summary.Elapsed < tt.duration || summary.Elapsed > tt.duration+time.Second {
+				t.Errorf("RoutineGet() elapsed = %v, want about %v", summary.Elapsed, tt.duration)
+			}
+			// Each worker sends a request about every 5ms until the time is up.
+			if most := int(tt.duration/(5*time.Millisecond)) * tt.concurrency; summary.Requests == 0 || summary.Requests > most {
+				t.Errorf("RoutineGet() requests = %d, want between 1 and %d", summary.Requests, most)
+			}
+			if got := summary.Errors[200]; got == nil || got.NumRequestWithErrorPerSecond != summary.Requests {
+				t.Errorf("RoutineGet() status 200 = %+v, want all the %d requests", got, summary.Requests)
+			}
+		})
+	}
+}
+
+func TestRoutineGet_Workers(t *testing.T) {
+	var mu sync.Mutex
+	conns := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		conns[r.RemoteAddr] = true
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}))
+	defer server.Close()
+	endpoints := []dto.Endpoint{{Name: "GET " + server.URL, Weight: 1, Method: "GET", URL: server.URL}}
+	tests := []struct {
+		name        string
+		requests    int
+		concurrency int
+	}{
+		{name: "One worker", requests: 10, concurrency: 1},
+		{name: "Fewer requests than workers", requests: 2, concurrency: 4},
+		{name: "Several workers", requests: 50, concurrency: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clear(conns)
+			dir := t.TempDir()
+			load := testLoad(endpoints, dir)
+			load.Requests, load.Concurrency = tt.requests, tt.concurrency
+			if err := RoutineGet(context.Background(), load); err != nil {
+				t.Fatalf("RoutineGet() error = %v", err)
+			}
+			summary := readSummary(t, filepath.Join(dir, "report.json"))
+			if len(summary.Workers) != tt.concurrency {
+				t.Errorf("RoutineGet() workers = %d, want %d", len(summary.Workers), tt.concurrency)
+			}
+			sent := 0
+			for _, w := range summary.Workers {
+				sent += w.Requests
+			}
+			if summary.Requests != tt.requests || sent != tt.requests {
+				t.Errorf("RoutineGet() requests = %d, sent by the workers %d, want %d", summary.Requests, sent, tt.requests)
+			}
+			// The workers keep their connections open from one request to the next.
+			if len(conns) > tt.concurrency {
+				t.Errorf("RoutineGet() opened %d connections, want at most one per worker, %d", len(conns), tt.concurrency)
+			}
+		})
+	}
+}
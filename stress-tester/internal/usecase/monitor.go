@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"stress-tester/internal/dto"
+	"stress-tester/internal/entity"
+	"stress-tester/internal/stats"
+	"sync"
+	"time"
+)
+
+// monitor keeps the requests that got a response during the widest window of the abort
+// conditions of a run, and when the request in flight of each worker was sent, and checks
+// the conditions against them every second while the run goes on.
+type monitor struct {
+	aborts   []dto.Abort
+	digits   int
+	window   time.Duration
+	mu       sync.Mutex
+	reds     []*dto.Red
+	inFlight map[int]time.Time
+}
+
+// newMonitor creates the monitor of the given abort conditions, evaluating latencies with
+// histograms of the given precision. It returns nil when there are none, as there is
+// nothing to monitor.
+func newMonitor(aborts []dto.Abort, digits int) *monitor {
+	if len(aborts) == 0 {
+		return nil
+	}
+	m := &monitor{aborts: aborts, digits: digits, inFlight: map[int]time.Time{}}
+	for _, a := range aborts {
+		m.window = max(m.window, a.Window)
+	}
+	return m
+}
+
+// add records a request that got a response. It is safe for concurrent use.
+func (m *monitor) add(r *dto.Red) {
+	m.mu.Lock()
+	m.reds = append(m.reds, r)
+	m.mu.Unlock()
+}
+
+// sending records that the worker with the given id sent a request at the given time,
+// replacing the one it had in flight. It is safe for concurrent use.
+func (m *monitor) sending(worker int, at time.Time) {
+	m.mu.Lock()
+	m.inFlight[worker] = at
+	m.mu.Unlock()
+}
+
+// idle records that the worker with the given id has no request in flight. It is safe for
+// concurrent use.
+func (m *monitor) idle(worker int) {
+	m.mu.Lock()
+	delete(m.inFlight, worker)
+	m.mu.Unlock()
+}
+
+// watch checks the abort conditions every second, each one only once the run has lasted
+// its whole window, until the context is canceled. When a condition holds it stops the
+// run with the reason and returns.
+func (m *monitor) watch(ctx context.Context, start time.Time, stop context.CancelCauseFunc) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := m.check(now, now.Sub(start)); err != nil {
+				stop(err)
+				return
+			}
+		}
+	}
+}
+
+// check returns the reason to stop the run when one of the conditions holds at now. A
+// request in flight counts as a response that took as long as it has waited so far.
+func (m *monitor) check(now time.Time, elapsed time.Duration) error {
+	m.mu.Lock()
+	i := 0
+	for i < len(m.reds) && now.Sub(m.reds[i].ReceivedAt) > m.window {
+		i++
+	}
+	m.reds = m.reds[i:]
+	reds := make([]*dto.Red, len(m.reds))
+	copy(reds, m.reds)
+	sent := make([]time.Time, 0, len(m.inFlight))
+	for _, at := range m.inFlight {
+		sent = append(sent, at)
+	}
+	m.mu.Unlock()
+
+	for _, a := range m.aborts {
+		if elapsed < a.Window {
+			continue
+		}
+		var window []*dto.Red
+		for _, r := range reds {
+			if now.Sub(r.ReceivedAt) <= a.Window {
+				window = append(window, r)
+			}
+		}
+		if entity.IsLatencyMetric(a.Condition.Metric) {
+			for _, at := range sent {
+				if waited := now.Sub(at); float64(waited) > a.Condition.Value {
+					window = append(window, &dto.Red{SentAt: at, ReceivedAt: now, Duration: waited})
+				}
+			}
+		}
+		result := stats.EvaluateThresholds(window, a.Window, []dto.Threshold{a.Condition}, m.digits)[0]
+		if result.Passed {
+			return fmt.Errorf("abort condition %s tripped: %s was %s over the last %s", a.Text, a.Condition.Metric, result.Actual, a.Window)
+		}
+	}
+	return nil
+}
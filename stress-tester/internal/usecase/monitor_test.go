@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"stress-tester/internal/dto"
+	"stress-tester/internal/entity"
+	"stress-tester/internal/stats"
+)
+
+func TestMonitor_check(t *testing.T) {
+	errorRate, _ := entity.ParseAbort("error_rate>50% for 10s")
+	p95, _ := entity.ParseAbort("p95>1s for 2s")
+	m := newMonitor([]dto.Abort{errorRate, p95}, stats.DefaultDigits)
+	if m.window != 10*time.Second {
+		t.Fatalf("monitor.window = %v, want 10s", m.window)
+	}
+
+	start := time.Now()
+	add := func(from, to int, status int, duration time.Duration) {
+		for i := from; i < to; i++ {
+			r := &dto.Red{ReceivedAt: start.Add(time.Duration(i) * time.Second), StatusCode: status, Duration: duration}
+			if status != 200 {
+				r.FailedCheck = "status 200"
+			}
+			m.add(r)
+		}
+	}
+
+	add(0, 12, 200, 100*time.Millisecond)
+	if err := m.check(start.Add(11*time.Second), 11*time.Second); err != nil {
+		t.Errorf("monitor.check() without errors error = %v", err)
+	}
+	add(12, 20, 500, 100*time.Millisecond)
+	if err := m.check(start.Add(19*time.Second), 5*time.Second); err != nil {
+		t.Errorf("monitor.check() before the window is full error = %v", err)
+	}
+	err := m.check(start.Add(19*time.Second), 19*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "error_rate was 72.73% over the last 10s") {
+		t.Errorf("monitor.check() error = %v, want the error rate tripped", err)
+	}
+	if len(m.reds) != 11 {
+		t.Errorf("monitor kept %d requests, want the 11 of the last 10s", len(m.reds))
+	}
+
+	m = newMonitor([]dto.Abort{p95}, stats.DefaultDigits)
+	add(0, 10, 200, 100*time.Millisecond)
+	if err := m.check(start.Add(9*time.Second), 9*time.Second); err != nil {
+		t.Errorf("monitor.check() of fast responses error = %v", err)
+	}
+	add(10, 12, 200, 3*time.Second)
+	err = m.check(start.Add(11*time.Second), 11*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "p95 was 3s over the last 2s") {
+		t.Errorf("monitor.check() error = %v, want the p95 tripped", err)
+	}
+
+	if newMonitor(nil, stats.DefaultDigits) != nil {
+		t.Errorf("newMonitor(nil) != nil")
+	}
+}
+
+func TestMonitor_check_inFlight(t *testing.T) {
+	p95, _ := entity.ParseAbort("p95>1s for 2s")
+	errorRate, _ := entity.ParseAbort("error_rate>50% for 2s")
+	now := time.Now()
+	tests := []struct {
+		name    string
+		abort   dto.Abort
+		sent    time.Duration
+		idle    bool
+		tripped string
+	}{
+		{name: "No response for longer than the latency", abort: p95, sent: 3 * time.Second, tripped: "p95 was 3s over the last 2s"},
+		{name: "In flight within the latency", abort: p95, sent: 500 * time.Millisecond},
+		{name: "Answered", abort: p95, sent: 3 * time.Second, idle: true},
+		{name: "Not a latency", abort: errorRate, sent: 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMonitor([]dto.Abort{tt.abort}, stats.DefaultDigits)
+			m.sending(0, now.Add(-tt.sent))
+			if tt.idle {
+				m.idle(0)
+			}
+			err := m.check(now, 10*time.Second)
+			if tt.tripped == "" && err != nil {
+				t.Errorf("monitor.check() error = %v, want none", err)
+			}
+			if tt.tripped != "" && (err == nil || !strings.Contains(err.Error(), tt.tripped)) {
+				t.Errorf("monitor.check() error = %v, want %q", err, tt.tripped)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"stress-tester/internal/db"
+	"stress-tester/internal/dto"
+	"stress-tester/internal/entity"
+	"stress-tester/internal/pool"
+	"stress-tester/internal/report"
+	"stress-tester/internal/stats"
+	"strings"
+)
+
+// RoutineReport writes the report of a run kept in a results database again, from its
+// records, see dto.Report, or lists the runs with q.List. It returns db.ErrRunNotFound
+// when there is no such run, or ErrThresholds when a threshold did not pass.
+func RoutineReport(q dto.Report) error {
+	// OpenDb would create the database when it does not exist.
+	if _, err := os.Stat(q.From); err != nil {
+		return fmt.Errorf("results database: %w", err)
+	}
+	database, err := pool.OpenDb(q.From)
+	if err != nil {
+		return fmt.Errorf("results database %s: %w", q.From, err)
+	}
+	store := db.NewDB(database, nil)
+	defer store.Close()
+
+	runs, err := store.Runs(q.Tags)
+	if err != nil {
+		return err
+	}
+	if q.List {
+		report.ReportRuns(runs)
+		return nil
+	}
+	run, err := pickRun(runs, q)
+	if err != nil {
+		return err
+	}
+	run.Path = q.From
+	load, err := parseRunLoad(run)
+	if err != nil {
+		return err
+	}
+	settings := stats.Settings{Start: run.StartedAt, Width: q.Interval, Digits: q.Precision, Percentiles: q.Percentiles}
+	store = store.WithRun(run.ID).WithSettings(settings)
+
+	// A run that never finished, as when the process was killed, ends with its last
+	// response.
+	if run.FinishedAt.IsZero() {
+		if run.FinishedAt, err = store.LastReceivedAt(); err != nil {
+			return err
+		}
+	}
+	// The elapsed time is the part of the run within the time window.
+	filter := db.Filter{Name: q.Endpoint, Status: q.Status}
+	from, to := run.StartedAt, run.FinishedAt
+	if q.Since > 0 {
+		filter.From = run.StartedAt.Add(q.Since)
+		from = filter.From
+	}
+	if q.Until > 0 {
+		filter.To = run.StartedAt.Add(q.Until)
+		if filter.To.Before(to) {
+			to = filter.To
+		}
+	}
+	elapsed := max(to.Sub(from), 0)
+
+	summary := dto.Summary{
+		Target:  strings.Join(run.Targets, ", "),
+		Elapsed: elapsed,
+		Run:     &run,
+	}
+	if q.Endpoint != "" {
+		summary.Target = q.Endpoint
+	}
+	journeys, err := store.JourneyNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range journeys {
+		a, err := store.AggregateJourney(name)
+		if err != nil {
+			return err
+		}
+		summary.Journeys = append(summary.Journeys, a.Result())
+	}
+
+	// The records are streamed once into the overall results and the recorder of the
+	// corrected percentiles, the latter backfilling the requests of a closed model like
+	// the run did.
+	all := stats.NewAggregator(settings)
+	recorder := stats.NewRecorder(run.StartedAt, q.Interval, q.Precision, load.Rate == 0)
+	counts := map[string]int{}
+	err = store.Each(filter, func(r *dto.Red) {
+		all.Add(r)
+		recorder.Record(r)
+		counts[r.Name]++
+	})
+	if err != nil {
+		return err
+	}
+	for i, stage := range load.stages {
+		result := dto.ResultStage{Stage: stage}
+		if i > 0 {
+			result.From = load.stages[i-1].Target
+		}
+		f := filter
+		f.Stage, f.ByStage = i, true
+		a, err := store.Aggregate(f)
+		if err != nil {
+			return err
+		}
+		if a.Count() > 0 {
+			result.Red = a.Red()
+			result.Percentiles = a.Percentiles()
+		}
+		summary.Stages = append(summary.Stages, result)
+	}
+	// The share of an endpoint is the one it got, the steps of journeys have none.
+	if len(counts) > 1 {
+		for _, name := range slices.Sorted(maps.Keys(counts)) {
+			share := float64(counts[name]) / float64(all.Count())
+			if len(journeys) > 0 {
+				share = 0
+			}
+			result, err := endpointResult(store, filter, dto.Endpoint{Name: name}, share)
+			if err != nil {
+				return err
+			}
+			summary.Endpoints = append(summary.Endpoints, result)
+		}
+	}
+	summary.Requests = all.Count()
+	if all.Count() > 0 {
+		summary.Red = all.Red()
+		summary.Errors = all.Errors()
+		summary.NetErrors = all.NetErrors()
+		summary.Percentiles = all.Percentiles()
+		summary.Corrected = recorder.Corrected().Percentiles(q.Percentiles)
+		summary.Phases = all.Phases()
+		summary.Sizes = all.Sizes()
+	}
+	summary.Thresholds = all.Thresholds(elapsed, q.Thresholds)
+	if err := report.Write(summary, settings, q.Outputs); err != nil {
+		return err
+	}
+	for _, t := range summary.Thresholds {
+		if !t.Passed {
+			return ErrThresholds
+		}
+	}
+	return nil
+}
+
+// pickRun returns the run of the report among the runs with its tags: the one with the id
+// of the report or, when it has none, the last one.
+func pickRun(runs []dto.Run, q dto.Report) (dto.Run, error) {
+	if q.Run == "" {
+		if len(runs) == 0 {
+			return dto.Run{}, fmt.Errorf("%w in %s with tags %q", db.ErrRunNotFound, q.From, q.Tags)
+		}
+		return runs[len(runs)-1], nil
+	}
+	for _, r := range runs {
+		if r.ID == q.Run {
+			return r, nil
+		}
+	}
+	if len(q.Tags) > 0 {
+		return dto.Run{}, fmt.Errorf("%w in %s with tags %q: %s", db.ErrRunNotFound, q.From, q.Tags, q.Run)
+	}
+	return dto.Run{}, fmt.Errorf("%w in %s: %s", db.ErrRunNotFound, q.From, q.Run)
+}
+
+// savedLoad is the load model of a run as kept in its metadata, with its stages parsed.
+type savedLoad struct {
+	runLoad
+	stages []dto.Stage
+}
+
+// parseRunLoad parses the load model kept in the metadata of the run. A run saved without
+// one has the zero load model.
+func parseRunLoad(run dto.Run) (savedLoad, error) {
+	var l savedLoad
+	if run.Load == "" {
+		return l, nil
+	}
+	if err := json.Unmarshal([]byte(run.Load), &l.runLoad); err != nil {
+		return l, fmt.Errorf("run %s: load: %w", run.ID, err)
+	}
+	if len(l.Stages) > 0 {
+		stages, err := entity.ParseStages(strings.Join(l.Stages, ","))
+		if err != nil {
+			return l, fmt.Errorf("run %s: load: %w", run.ID, err)
+		}
+		l.stages = stages
+	}
+	return l, nil
+}
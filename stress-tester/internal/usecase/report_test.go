@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"stress-tester/internal/db"
+	"stress-tester/internal/dto"
+	"stress-tester/internal/pool"
+	"stress-tester/internal/stats"
+)
+
+func TestRoutineReport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.db")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	red := func(name string, at time.Duration, status int) *dto.Red {
+		return &dto.Red{Name: name, SentAt: start.Add(at), ReceivedAt: start.Add(at + 10*time.Millisecond), StatusCode: status, Duration: 10 * time.Millisecond}
+	}
+	runs := []struct {
+		run  dto.Run
+		reds []*dto.Red
+	}{
+		{
+			run: dto.Run{ID: "first", StartedAt: start, FinishedAt: start.Add(3 * time.Second), Tags: []string{"nightly"}},
+			reds: []*dto.Red{
+				red("products", 0, 200),
+				red("products", 1500*time.Millisecond, 500),
+				red("cart", 500*time.Millisecond, 200),
+				red("cart", 2500*time.Millisecond, -1),
+			},
+		},
+		{
+			run:  dto.Run{ID: "second", StartedAt: start.Add(time.Hour), FinishedAt: start.Add(time.Hour + time.Second)},
+			reds: []*dto.Red{red("products", time.Hour, 200)},
+		},
+		{
+			// Killed during its second stage, it never finished.
+			run: dto.Run{ID: "killed", StartedAt: start.Add(-time.Hour), Load: `{"stages":["1s:2","1s:0"]}`},
+			reds: []*dto.Red{
+				{Name: "products", SentAt: start.Add(-time.Hour), ReceivedAt: start.Add(-time.Hour + 10*time.Millisecond), StatusCode: 200, Duration: 10 * time.Millisecond},
+				{Name: "products", SentAt: start.Add(-time.Hour + 1500*time.Millisecond), ReceivedAt: start.Add(-time.Hour + 1510*time.Millisecond), StatusCode: 200, Duration: 10 * time.Millisecond, Stage: 1},
+			},
+		},
+	}
+	database, err := pool.OpenDb(path)
+	if err != nil {
+		t.Fatalf("OpenDb() error = %v", err)
+	}
+	store := db.NewDB(database, nil)
+	for _, r := range runs {
+		if err := store.StartRun(r.run); err != nil {
+			t.Fatalf("StartRun() error = %v", err)
+		}
+		if err := store.AppendBatch(r.reds, nil); err != nil {
+			t.Fatalf("AppendBatch() error = %v", err)
+		}
+		if r.run.FinishedAt.IsZero() {
+			continue
+		}
+		if err := store.FinishRun(r.run.ID, r.run.FinishedAt); err != nil {
+			t.Fatalf("FinishRun() error = %v", err)
+		}
+	}
+	store.Close()
+
+	tests := []struct {
+		name      string
+		report    dto.Report
+		run       string
+		requests  int
+		endpoints int
+		stages    int
+		elapsed   time.Duration
+		err       error
+	}{
+		{name: "Last run", report: dto.Report{}, run: "second", requests: 1},
+		{name: "By id", report: dto.Report{Run: "first"}, run: "first", requests: 4, endpoints: 2, elapsed: 3 * time.Second},
+		{name: "By tag", report: dto.Report{Tags: []string{"nightly"}}, run: "first", requests: 4, endpoints: 2},
+		{name: "By endpoint", report: dto.Report{Run: "first", Endpoint: "cart"}, run: "first", requests: 2},
+		{name: "By time", report: dto.Report{Run: "first", Since: time.Second, Until: 2 * time.Second}, run: "first", requests: 1},
+		{name: "By status", report: dto.Report{Run: "first", Status: "2xx"}, run: "first", requests: 2, endpoints: 2},
+		{name: "No match", report: dto.Report{Run: "first", Endpoint: "checkout"}, run: "first", requests: 0},
+		{name: "Unfinished", report: dto.Report{Run: "killed"}, run: "killed", requests: 2, stages: 2, elapsed: 1510 * time.Millisecond},
+		{name: "Threshold", report: dto.Report{Run: "first", Thresholds: []dto.Threshold{{Text: "status_5xx<1", Metric: "status_5xx", Op: "<", Value: 1}}}, run: "first", requests: 4, endpoints: 2},
+		{name: "Missing run", report: dto.Report{Run: "third"}, err: db.ErrRunNotFound},
+		{name: "Missing tag", report: dto.Report{Run: "second", Tags: []string{"nightly"}}, err: db.ErrRunNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(dir, "report.json")
+			tt.report.From = path
+			tt.report.Interval = time.Second
+			tt.report.Precision = stats.DefaultDigits
+			tt.report.Percentiles = []float64{50, 99}
+			tt.report.Outputs = []dto.Output{{Format: "json", Path: out}}
+			err := RoutineReport(tt.report)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("RoutineReport() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if len(tt.report.Thresholds) > 0 {
+				if !errors.Is(err, ErrThresholds) {
+					t.Fatalf("RoutineReport() error = %v, want %v", err, ErrThresholds)
+				}
+			} else if err != nil {
+				t.Fatalf("RoutineReport() error = %v", err)
+			}
+			b, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			var summary dto.Summary
+			if err := json.Unmarshal(b, &summary); err != nil {
+				t.Fatalf("report is not JSON: %v", err)
+			}
+			if summary.Run == nil || summary.Run.ID != tt.run || summary.Requests != tt.requests || len(summary.Endpoints) != tt.endpoints {
+				t.Errorf("RoutineReport() reported run %+v, %d requests, %d endpoints, want run %s, %d requests, %d endpoints", summary.Run, summary.Requests, len(summary.Endpoints), tt.run, tt.requests, tt.endpoints)
+			}
+			if len(summary.Stages) != tt.stages {
+				t.Errorf("RoutineReport() reported %d stages, want %d", len(summary.Stages), tt.stages)
+			}
+			for i, s := range summary.Stages {
+				if len(s.Red) != 1 {
+					t.Errorf("RoutineReport() stage %d = %+v, want the request sent during it", i, s)
+				}
+			}
+			if tt.elapsed > 0 && summary.Elapsed != tt.elapsed {
+				t.Errorf("RoutineReport() elapsed = %v, want %v", summary.Elapsed, tt.elapsed)
+			}
+		})
+	}
+}
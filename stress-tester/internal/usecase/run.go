@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"stress-tester/internal/dto"
+	"time"
+)
+
+// runLoad is the load model of a run as kept in the metadata of the run, in the format
+// of the load of a plan file.
+type runLoad struct {
+	Requests    int      `json:"requests,omitempty"`
+	Concurrency int      `json:"concurrency,omitempty"`
+	Duration    string   `json:"duration,omitempty"`
+	Rate        float64  `json:"rate,omitempty"`
+	MaxInFlight int      `json:"max_in_flight,omitempty"`
+	Stages      []string `json:"stages,omitempty"`
+	Timeout     string   `json:"timeout,omitempty"`
+}
+
+// newRun returns the metadata of a run of the load started at the given time, with an id
+// made of the start time and a random suffix, so that the runs kept in the same results
+// database sort by start time and do not collide.
+func newRun(load dto.Load, start time.Time) (dto.Run, error) {
+	l := runLoad{}
+	switch {
+	case load.Rate > 0:
+		l.Rate, l.MaxInFlight = load.Rate, load.MaxInFlight
+	case len(load.Stages) > 0:
+		for _, s := range load.Stages {
+			l.Stages = append(l.Stages, fmt.Sprint(s.Duration, ":", s.Target))
+		}
+	default:
+		l.Concurrency = load.Concurrency
+	}
+	switch {
+	case len(l.Stages) > 0:
+	case load.Duration > 0:
+		l.Duration = load.Duration.String()
+	default:
+		l.Requests = load.Requests
+	}
+	if load.Timeout > 0 {
+		l.Timeout = load.Timeout.String()
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return dto.Run{}, err
+	}
+
+	var targets []string
+	for _, e := range load.Endpoints {
+		targets = append(targets, e.Method+" "+e.URL)
+	}
+	for _, j := range load.Journeys {
+		for _, s := range j.Steps {
+			targets = append(targets, s.Method+" "+s.URL)
+		}
+	}
+	return dto.Run{
+		ID:        fmt.Sprintf("%s-%04x", start.UTC().Format("20060102-150405"), rand.IntN(1<<16)),
+		StartedAt: start,
+		Targets:   targets,
+		Load:      string(b),
+		Version:   load.Version,
+		GitSHA:    load.GitSHA,
+		Tags:      load.Tags,
+		Path:      load.Store.Path,
+	}, nil
+}
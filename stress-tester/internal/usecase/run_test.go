@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"stress-tester/internal/dto"
+)
+
+func TestNewRun(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	endpoints := []dto.Endpoint{{Method: "GET", URL: "http://localhost/products"}, {Method: "POST", URL: "http://localhost/orders"}}
+	tests := []struct {
+		name    string
+		load    dto.Load
+		runLoad string
+		targets []string
+	}{
+		{
+			name:    "Requests",
+			load:    dto.Load{Endpoints: endpoints[:1], Requests: 100, Concurrency: 10, Timeout: 2 * time.Second},
+			runLoad: `{"requests":100,"concurrency":10,"timeout":"2s"}`,
+			targets: []string{"GET http://localhost/products"},
+		},
+		{
+			name:    "Rate",
+			load:    dto.Load{Endpoints: endpoints, Requests: 100, Concurrency: 10, Duration: time.Minute, Rate: 50, MaxInFlight: 20},
+			runLoad: `{"duration":"1m0s","rate":50,"max_in_flight":20}`,
+			targets: []string{"GET http://localhost/products", "POST http://localhost/orders"},
+		},
+		{
+			name:    "Stages",
+			load:    dto.Load{Journeys: []dto.Journey{{Name: "flow", Steps: endpoints}}, Requests: 100, Concurrency: 10, Stages: []dto.Stage{{Duration: time.Minute, Target: 10}, {Duration: 30 * time.Second, Target: 0}}},
+			runLoad: `{"stages":["1m0s:10","30s:0"]}`,
+			targets: []string{"GET http://localhost/products", "POST http://localhost/orders"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.load.Version, tt.load.GitSHA, tt.load.Tags = "dev", "3f2c1ab", []string{"nightly"}
+			tt.load.Store = dto.Store{Kind: "sqlite", Path: "results.db"}
+			got, err := newRun(tt.load, start)
+			if err != nil {
+				t.Fatalf("newRun() error = %v", err)
+			}
+			if !strings.HasPrefix(got.ID, "20240102-030405-") {
+				t.Errorf("newRun() id = %s, want the start time as prefix", got.ID)
+			}
+			want := dto.Run{ID: got.ID, StartedAt: start, Targets: tt.targets, Load: tt.runLoad, Version: "dev", GitSHA: "3f2c1ab", Tags: []string{"nightly"}, Path: "results.db"}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("newRun() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
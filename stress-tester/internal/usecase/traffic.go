@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"stress-tester/internal/dto"
+	"stress-tester/internal/entity"
+	"time"
+)
+
+// traffic is what the workers of a run send: one of the endpoints of the load, or one
+// run of one of its journeys, picked at random by weight, rendered with the next row of
+// the feeders it uses, and verified with its checks.
+type traffic struct {
+	endpoints []dto.Endpoint
+	checks    []entity.Checks
+	journeys  []*entity.Journey
+	mix       *entity.Mix
+	feeders   [][]*entity.Feeder
+}
+
+// newTraffic builds the traffic of the load, compiling the checks of its endpoints and the
+// checks and extractors of its journeys, and finding the feeders each one uses. A load
+// with journeys runs only its journeys.
+func newTraffic(load dto.Load) (*traffic, error) {
+	feeders := make([]*entity.Feeder, len(load.Feeders))
+	for i, f := range load.Feeders {
+		feeder, err := entity.NewFeeder(f)
+		if err != nil {
+			return nil, err
+		}
+		feeders[i] = feeder
+	}
+	uses := func(endpoints ...dto.Endpoint) []*entity.Feeder {
+		var used []*entity.Feeder
+		for _, f := range feeders {
+			if f.UsedBy(endpoints...) {
+				used = append(used, f)
+			}
+		}
+		return used
+	}
+
+	t := &traffic{}
+	var weights []int
+	if len(load.Journeys) > 0 {
+		for _, j := range load.Journeys {
+			journey, err := entity.NewJourney(j)
+			if err != nil {
+				return nil, err
+			}
+			t.journeys = append(t.journeys, journey)
+			t.feeders = append(t.feeders, uses(j.Steps...))
+			weights = append(weights, j.Weight)
+		}
+	} else {
+		t.endpoints = load.Endpoints
+		for _, e := range load.Endpoints {
+			checks, err := entity.NewChecks(e)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", e.Name, err)
+			}
+			t.checks = append(t.checks, checks)
+			t.feeders = append(t.feeders, uses(e))
+			weights = append(weights, e.Weight)
+		}
+	}
+	t.mix = entity.NewMix(weights)
+	return t, nil
+}
+
+// next sends the next request, or runs the next journey, meant to be sent at intended,
+// passing each result to record and the journey to done. It returns the number of
+// requests sent, or the error of a feeder that ran out of rows.
+func (t *traffic) next(ctx context.Context, client *http.Client, stage int, intended time.Time, record func(*dto.Red), done func(*dto.JourneyRed)) (int, error) {
+	i := t.mix.Pick()
+	vars, err := t.row(i)
+	if err != nil {
+		return 0, err
+	}
+	if t.journeys == nil {
+		send(ctx, client, entity.RenderEndpoint(t.endpoints[i], vars), t.checks[i], stage, intended, record)
+		return 1, nil
+	}
+
+	j := t.journeys[i]
+	requests := 0
+	start := time.Now()
+	err = j.Run(ctx, client, vars, func(step dto.Endpoint, r *entity.Red) {
+		// Only the first step has a schedule to be late on, the others follow it.
+		if requests > 0 {
+			intended = r.SentAt
+		}
+		requests++
+		record(newRed(step.Name, intended, stage, r))
+	})
+	if err != nil {
+		slog.Debug("journey failed", "journey", j.Name, "msg", err.Error())
+	}
+	finish := time.Now()
+	done(&dto.JourneyRed{Name: j.Name, StartedAt: start, FinishedAt: finish, Duration: finish.Sub(start), Failed: err != nil, Stage: stage})
+	return requests, nil
+}
+
+// row returns the variables of the next row of every feeder used by the endpoint, or
+// journey, at index i of the mix.
+func (t *traffic) row(i int) (map[string]string, error) {
+	feeders := t.feeders[i]
+	switch len(feeders) {
+	case 0:
+		return nil, nil
+	case 1:
+		return feeders[0].Next()
+	}
+	vars := map[string]string{}
+	for _, f := range feeders {
+		row, err := f.Next()
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range row {
+			vars[k] = v
+		}
+	}
+	return vars, nil
+}
+
+// results returns how many responses passed and failed each check of the endpoints, or
+// of the steps of the journeys, that declare checks.
+func (t *traffic) results() []dto.ResultCheck {
+	var results []dto.ResultCheck
+	add := func(e dto.Endpoint, checks entity.Checks) {
+		if len(e.Checks) == 0 {
+			return
+		}
+		for _, c := range checks {
+			passed, failed := c.Result()
+			results = append(results, dto.ResultCheck{Endpoint: e.Name, Check: c.Name, Passed: passed, Failed: failed})
+		}
+	}
+	for i, e := range t.endpoints {
+		add(e, t.checks[i])
+	}
+	for _, j := range t.journeys {
+		for _, s := range j.Steps {
+			add(s.Endpoint, s.Checks)
+		}
+	}
+	return results
+}
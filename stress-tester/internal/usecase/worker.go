@@ -0,0 +1,273 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"stress-tester/internal/db"
+	"stress-tester/internal/dto"
+	"stress-tester/internal/entity"
+	"stress-tester/internal/stats"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// job is a single request handed to a worker. intended is when the dispatcher meant it to
+// be sent, zero when the dispatcher has no schedule, as in a closed model, where a request
+// is meant to be sent as soon as a worker takes it.
+type job struct {
+	intended time.Time
+}
+
+// stageState is shared by the workers of a run that follows a load profile: the current
+// stage and how many workers are active, the others waiting until changed is closed or
+// over is closed once no more jobs will be dispatched.
+type stageState struct {
+	stage   atomic.Int64
+	active  atomic.Int64
+	over    chan struct{}
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+// set sets the current stage and the number of active workers, waking up the waiting
+// workers when the latter changes.
+func (s *stageState) set(stage, active int) {
+	s.stage.Store(int64(stage))
+	if s.active.Swap(int64(active)) == int64(active) {
+		return
+	}
+	s.mu.Lock()
+	if s.changed != nil {
+		close(s.changed)
+		s.changed = nil
+	}
+	s.mu.Unlock()
+}
+
+// changes returns a channel closed the next time the number of active workers changes.
+func (s *stageState) changes() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.changed == nil {
+		s.changed = make(chan struct{})
+	}
+	return s.changed
+}
+
+type worker struct {
+	ID       int
+	Client   *http.Client
+	Traffic  *traffic
+	Ingest   *db.Ingest
+	Stop     context.CancelCauseFunc
+	Monitor  *monitor
+	Recorder *stats.Recorder
+	Stages   *stageState
+	Requests int
+	Canceled int
+	Busy     time.Duration
+}
+
+// newWorker creates a worker with the given id, http client, traffic and stage state,
+// nil without a load profile. stop stops the whole run when the traffic can not go on.
+func newWorker(id int, client *http.Client, traffic *traffic, stages *stageState, ingest *db.Ingest, stop context.CancelCauseFunc, monitor *monitor, recorder *stats.Recorder) *worker {
+	return &worker{
+		ID:       id,
+		Client:   client,
+		Traffic:  traffic,
+		Ingest:   ingest,
+		Stop:     stop,
+		Monitor:  monitor,
+		Recorder: recorder,
+		Stages:   stages,
+	}
+}
+
+// run takes jobs from the channel one at a time until it is closed or ctx is canceled.
+// Canceling sending cancels the request in flight, which is then counted apart instead
+// of recorded, as its failure says nothing about the target.
+func (w *worker) run(ctx, sending context.Context, jobs <-chan job, wg *sync.WaitGroup) {
+	defer wg.Done()
+	record := func(r *dto.Red) {
+		if r.NetError == dto.NetErrorCanceled && sending.Err() != nil {
+			w.Canceled++
+			return
+		}
+		w.record(r)
+	}
+	done := func(j *dto.JourneyRed) {
+		if j.Failed && sending.Err() != nil {
+			return
+		}
+		w.Ingest.AddJourney(j)
+	}
+	for {
+		if w.Stages != nil {
+			// Takes the channel before reading active, so a change in between is not missed.
+			changed := w.Stages.changes()
+			if int64(w.ID) >= w.Stages.active.Load() {
+				select {
+				case <-ctx.Done():
+					return
+				case <-w.Stages.over:
+					return
+				case <-changed:
+					continue
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case j, ok := <-jobs:
+			if !ok {
+				return
+			}
+			stage := 0
+			if w.Stages != nil {
+				stage = int(w.Stages.stage.Load())
+			}
+			start := time.Now()
+			intended := j.intended
+			if intended.IsZero() {
+				intended = start
+			}
+			if w.Monitor != nil {
+				w.Monitor.sending(w.ID, start)
+			}
+			canceled := w.Canceled
+			requests, err := w.Traffic.next(sending, w.Client, stage, intended, record, done)
+			if w.Monitor != nil {
+				w.Monitor.idle(w.ID)
+			}
+			if err != nil {
+				w.Stop(err)
+				return
+			}
+			w.Requests += requests - (w.Canceled - canceled)
+			w.Busy += time.Since(start)
+		}
+	}
+}
+
+// record adds the result of a request to the pipeline, adding it to the monitor and the
+// recorder first. For the monitor the next step of a journey, if any, is sent right away.
+func (w *worker) record(r *dto.Red) {
+	if w.Monitor != nil {
+		w.Monitor.add(r)
+		w.Monitor.sending(w.ID, time.Now())
+	}
+	w.Recorder.Record(r)
+	w.Ingest.Add(r)
+}
+
+// result returns the summary of the work done by the worker.
+func (w *worker) result() dto.ResultWorker {
+	return dto.ResultWorker{ID: w.ID, Requests: w.Requests, Busy: w.Busy}
+}
+
+// dispatchRequests hands out the given number of jobs, as fast as the workers take them,
+// and closes the channel. It stops early when the context is canceled.
+func dispatchRequests(ctx context.Context, jobs chan<- job, requests int) {
+	defer close(jobs)
+	for range requests {
+		select {
+		case <-ctx.Done():
+			return
+		case jobs <- job{}:
+		}
+	}
+}
+
+// dispatchForDuration hands out jobs, as fast as the workers take them, until the given
+// duration has elapsed, or the context is canceled, and closes the channel. Requests
+// already taken by a worker when the time is up are allowed to finish.
+func dispatchForDuration(ctx context.Context, jobs chan<- job, duration time.Duration) {
+	deadline := time.NewTimer(duration)
+	defer deadline.Stop()
+	defer close(jobs)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			return
+		case jobs <- job{}:
+		}
+	}
+}
+
+// followProfile updates the stage state every 100ms with the current stage and number of
+// active workers of the given load profile, printing a line whenever a new stage starts,
+// until the profile is over or the context is canceled.
+func followProfile(ctx context.Context, profile *entity.LoadProfile, state *stageState) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	start := time.Now()
+	current := -1
+	for {
+		stage, concurrency, ok := profile.At(time.Since(start))
+		state.set(stage, concurrency)
+		if !ok {
+			return
+		}
+		if stage != current {
+			current = stage
+			fmt.Println("Stage ", stage+1, " moving to ", profile.Stages[stage].Target, " workers over ", profile.Stages[stage].Duration)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchAtRate schedules jobs at load.Rate requests per second, whatever the response
+// times are, until load.Requests or load.Duration, and closes the channel. A job no worker
+// takes before the next one is due is dropped.
+func dispatchAtRate(ctx context.Context, jobs chan<- job, load dto.Load) dto.ResultSchedule {
+	defer close(jobs)
+	result := dto.ResultSchedule{Rate: load.Rate, MaxInFlight: load.MaxInFlight}
+	interval := time.Duration(float64(time.Second) / load.Rate)
+
+	start := time.Now()
+	for i := 0; ; i++ {
+		intended := start.Add(time.Duration(i) * interval)
+		if load.Duration > 0 && intended.Sub(start) >= load.Duration {
+			break
+		}
+		if load.Duration == 0 && i >= load.Requests {
+			break
+		}
+		wait := time.NewTimer(time.Until(intended))
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return result
+		case <-wait.C:
+		}
+		result.Scheduled++
+
+		select {
+		case jobs <- job{intended: intended}:
+		default:
+			timer := time.NewTimer(time.Until(intended.Add(interval)))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return result
+			case jobs <- job{intended: intended}:
+				timer.Stop()
+				result.Late++
+			case <-timer.C:
+				result.Dropped++
+				continue
+			}
+		}
+		result.Sent++
+	}
+	return result
+}
@@ -0,0 +1,311 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"stress-tester/internal/db"
+	"stress-tester/internal/dto"
+	"stress-tester/internal/entity"
+	"stress-tester/internal/stats"
+)
+
+// taken is a job as taken by a fake worker, with the time it took it.
+type taken struct {
+	job job
+	at  time.Time
+}
+
+// fakeWorkers starts n fake workers taking the jobs from the channel, each one busy for
+// work after taking a job, and returns the jobs they took once the channel is closed.
+func fakeWorkers(n int, work time.Duration, jobs <-chan job) func() []taken {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var all []taken
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				mu.Lock()
+				all = append(all, taken{job: j, at: time.Now()})
+				mu.Unlock()
+				time.Sleep(work)
+			}
+		}()
+	}
+	// Lets the workers start waiting for jobs, as the workers of a run do before the
+	// dispatcher starts.
+	time.Sleep(10 * time.Millisecond)
+	return func() []taken {
+		wg.Wait()
+		return all
+	}
+}
+
+func TestDispatchAtRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		load    dto.Load
+		workers int
+		work    time.Duration
+		want    dto.ResultSchedule
+	}{
+		{
+			name:    "On time",
+			load:    dto.Load{Rate: 50, MaxInFlight: 2, Requests: 5},
+			workers: 2,
+			work:    10 * time.Millisecond,
+			want:    dto.ResultSchedule{Rate: 50, MaxInFlight: 2, Scheduled: 5, Sent: 5},
+		},
+		{
+			// The worker frees up 40ms after the second request is due, before the third one.
+			name:    "Late",
+			load:    dto.Load{Rate: 10, MaxInFlight: 1, Requests: 2},
+			workers: 1,
+			work:    140 * time.Millisecond,
+			want:    dto.ResultSchedule{Rate: 10, MaxInFlight: 1, Scheduled: 2, Sent: 2, Late: 1},
+		},
+		{
+			// The worker is still busy when the third request is due, so the second one is
+			// dropped, and the third one is sent late when it frees up 60ms later.
+			name:    "Dropped",
+			load:    dto.Load{Rate: 10, MaxInFlight: 1, Requests: 3},
+			workers: 1,
+			work:    260 * time.Millisecond,
+			want:    dto.ResultSchedule{Rate: 10, MaxInFlight: 1, Scheduled: 3, Sent: 2, Late: 1, Dropped: 1},
+		},
+		{
+			name:    "Duration",
+			load:    dto.Load{Rate: 20, MaxInFlight: 1, Duration: 200 * time.Millisecond},
+			workers: 1,
+			work:    time.Millisecond,
+			want:    dto.ResultSchedule{Rate: 20, MaxInFlight: 1, Scheduled: 4, Sent: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := make(chan job)
+			wait := fakeWorkers(tt.workers, tt.work, jobs)
+			got := dispatchAtRate(context.Background(), jobs, tt.load)
+			if got != tt.want {
+				t.Errorf("dispatchAtRate() = %+v, want %+v", got, tt.want)
+			}
+			all := wait()
+			if len(all) != got.Sent {
+				t.Errorf("dispatchAtRate() sent %d jobs, want %d", len(all), got.Sent)
+			}
+			// Every job keeps its slot of the schedule and is taken before the next slot is
+			// due: the requests that could not be sent in time are not sent in a burst later.
+			interval := time.Duration(float64(time.Second) / tt.load.Rate)
+			start := all[0].job.intended
+			for i, tk := range all {
+				if offset := tk.job.intended.Sub(start); offset%interval != 0 {
+					t.Errorf("job %d intended at %v from the start, want a multiple of %v", i, offset, interval)
+				}
+				if i > 0 && !tk.job.intended.After(all[i-1].job.intended) {
+					t.Errorf("job %d intended at %v, want after %v", i, tk.job.intended, all[i-1].job.intended)
+				}
+				if late := tk.at.Sub(tk.job.intended); late < 0 || late >= interval {
+					t.Errorf("job %d taken %v after it was due, want less than %v", i, late, interval)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatchAtRate_Canceled(t *testing.T) {
+	jobs := make(chan job)
+	wait := fakeWorkers(1, 0, jobs)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	got := dispatchAtRate(ctx, jobs, dto.Load{Rate: 10, MaxInFlight: 1, Duration: time.Minute})
+	if got.Scheduled != 1 || got.Sent != 1 || len(wait()) != 1 {
+		t.Errorf("dispatchAtRate() = %+v, want 1 job before it was canceled", got)
+	}
+}
+
+func TestDispatchRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests int
+		cancelAt int
+		want     int
+	}{
+		{name: "All", requests: 5, want: 5},
+		{name: "None", requests: 0, want: 0},
+		{name: "Canceled", requests: 5, cancelAt: 3, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			jobs := make(chan job)
+			returned := make(chan struct{})
+			done := make(chan int)
+			go func() {
+				n := 0
+				for range jobs {
+					n++
+					// Stops taking jobs until the dispatcher returns once the context is
+					// canceled, so the cancellation is all it can pick.
+					if n == tt.cancelAt {
+						cancel()
+						<-returned
+					}
+				}
+				done <- n
+			}()
+			dispatchRequests(ctx, jobs, tt.requests)
+			close(returned)
+			if got := <-done; got != tt.want {
+				t.Errorf("dispatchRequests() handed out %d jobs, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatchForDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		cancel   time.Duration
+		min, max time.Duration
+	}{
+		{name: "Elapsed", duration: 100 * time.Millisecond, min: 100 * time.Millisecond, max: 300 * time.Millisecond},
+		{name: "Canceled", duration: time.Minute, cancel: 50 * time.Millisecond, min: 50 * time.Millisecond, max: 250 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := make(chan job)
+			wait := fakeWorkers(2, 10*time.Millisecond, jobs)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel > 0 {
+				time.AfterFunc(tt.cancel, cancel)
+			}
+			start := time.Now()
+			dispatchForDuration(ctx, jobs, tt.duration)
+			elapsed := time.Since(start)
+			all := wait()
+			if elapsed < tt.min || elapsed > tt.max {
+				t.Errorf("dispatchForDuration() took %v, want between %v and %v", elapsed, tt.min, tt.max)
+			}
+			// Two workers busy 10ms per job take about 2 jobs every 10ms.
+			if want := int(tt.min / (5 * time.Millisecond)); len(all) < want/2 || len(all) > want*2 {
+				t.Errorf("dispatchForDuration() handed out %d jobs, want about %d", len(all), want)
+			}
+		})
+	}
+}
+
+func TestFollowProfile(t *testing.T) {
+	profile := &entity.LoadProfile{Stages: []dto.Stage{{Duration: 200 * time.Millisecond, Target: 4}, {Duration: 200 * time.Millisecond, Target: 1}}}
+	state := &stageState{}
+	changed := state.changes()
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		followProfile(context.Background(), profile, state)
+		close(done)
+	}()
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Errorf("followProfile() did not wake up the waiting workers")
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if stage, active := state.stage.Load(), state.active.Load(); stage != 1 || active < 1 || active > 4 {
+		t.Errorf("followProfile() at 300ms = stage %d with %d workers, want stage 1 ramping down from 4 to 1", stage, active)
+	}
+	<-done
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("followProfile() returned after %v, want after the 400ms of the profile", elapsed)
+	}
+	if stage, active := state.stage.Load(), state.active.Load(); stage != 1 || active != 1 {
+		t.Errorf("followProfile() = stage %d with %d workers, want the last stage with 1 worker", stage, active)
+	}
+}
+
+func TestFollowProfile_Canceled(t *testing.T) {
+	profile := &entity.LoadProfile{Stages: []dto.Stage{{Duration: time.Minute, Target: 1}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	followProfile(ctx, profile, &stageState{})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("followProfile() returned after %v, want soon after the context was canceled", elapsed)
+	}
+}
+
+func TestWorker_run(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	endpoint := dto.Endpoint{Name: "users", Weight: 1, Method: "GET", URL: server.URL + "/users/{{users.id}}"}
+	tests := []struct {
+		name     string
+		mode     string
+		jobs     int
+		active   int64
+		requests int
+		stopped  error
+	}{
+		{name: "Until the channel is closed", mode: "sequential", jobs: 3, requests: 3},
+		{name: "Inactive", mode: "sequential", jobs: 3, active: -1, requests: 0},
+		{name: "Feeder ran out", mode: "unique", jobs: 3, requests: 2, stopped: entity.ErrFeederExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			load := dto.Load{
+				Endpoints: []dto.Endpoint{endpoint},
+				Feeders:   []dto.Feeder{{Name: "users", Mode: tt.mode, Rows: []map[string]string{{"id": "1"}, {"id": "2"}}}},
+			}
+			traffic, err := newTraffic(load)
+			if err != nil {
+				t.Fatalf("newTraffic() error = %v", err)
+			}
+			var stages *stageState
+			if tt.active < 0 {
+				stages = &stageState{over: make(chan struct{})}
+				close(stages.over)
+			}
+			start := time.Now()
+			store := db.NewMemory(stats.Settings{Start: start, Width: time.Second, Digits: 3})
+			ingest := db.NewIngest(store, 10, 10, time.Millisecond)
+			run, stop := context.WithCancelCause(context.Background())
+			defer stop(nil)
+			abort, _ := entity.ParseAbort("p95>1s for 2s")
+			monitor := newMonitor([]dto.Abort{abort}, 3)
+			w := newWorker(0, server.Client(), traffic, stages, ingest, stop, monitor, stats.NewRecorder(start, time.Second, 3, true))
+
+			jobs := make(chan job, tt.jobs)
+			for range tt.jobs {
+				jobs <- job{}
+			}
+			close(jobs)
+			var wg sync.WaitGroup
+			wg.Add(1)
+			w.run(run, context.Background(), jobs, &wg)
+			wg.Wait()
+
+			if w.Requests != tt.requests || w.Recorder.Total().Count() != tt.requests {
+				t.Errorf("worker.run() requests = %d, recorded %d, want %d", w.Requests, w.Recorder.Total().Count(), tt.requests)
+			}
+			if got := ingest.Close(); got.Records != tt.requests {
+				t.Errorf("worker.run() stored %d records, want %d", got.Records, tt.requests)
+			}
+			if len(monitor.reds) != tt.requests || len(monitor.inFlight) != 0 {
+				t.Errorf("worker.run() monitored %d requests, %d in flight, want %d, none in flight", len(monitor.reds), len(monitor.inFlight), tt.requests)
+			}
+			if got := context.Cause(run); !errors.Is(got, tt.stopped) {
+				t.Errorf("worker.run() stopped the run with %v, want %v", got, tt.stopped)
+			}
+		})
+	}
+}